@@ -0,0 +1,34 @@
+package notifier
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// Command runs a shell command for every message, the message is passed in
+// the TASK_TITLE, TASK_BODY, TASK_ID, TASK_UID and TASK_DESCRIPTION variables
+type Command struct {
+	Command string
+}
+
+// Notify run the command and wait for it
+func (c *Command) Notify(msg Message) error {
+	cmd := exec.Command("sh", "-c", c.Command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", c.Command)
+	}
+	cmd.Env = append(os.Environ(),
+		"TASK_TITLE="+msg.Title,
+		"TASK_BODY="+msg.Body,
+		"TASK_ID="+strconv.Itoa(msg.Task.Id),
+		"TASK_UID="+msg.Task.UID,
+		"TASK_DESCRIPTION="+msg.Task.Description,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify command failed: %v: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package notifier
+
+import (
+	"github.com/0xAX/notificator"
+)
+
+// Desktop pushes a desktop notification
+type Desktop struct {
+	AppName string
+	// Icon is the path of the notification icon, the system default when empty
+	Icon string
+}
+
+// Notify push the message to the desktop
+func (d *Desktop) Notify(msg Message) error {
+	n := notificator.New(notificator.Options{
+		DefaultIcon: d.Icon,
+		AppName:     d.AppName,
+	})
+	return n.Push(msg.Title, msg.Body, d.Icon, notificator.UR_NORMAL)
+}
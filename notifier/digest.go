@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+// digestSection is a titled list of tasks in the digest, with the time
+// shown next to each task
+type digestSection struct {
+	Title string
+	Tasks taskmanager.Tasks
+	When  func(task taskmanager.Task) string
+}
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>{{.Subject}}</h2>
+{{range .Sections}}<h3>{{.Title}} ({{len .Tasks}})</h3>
+{{if .Tasks}}<table cellpadding="4">
+{{$when := .When}}{{range .Tasks}}<tr><td>{{.Id}}</td><td>{{.Description}}</td><td>{{call $when .}}</td></tr>
+{{end}}</table>
+{{else}}<p>None</p>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// DigestSubject return the subject line of a digest
+func DigestSubject(d taskmanager.Digest) string {
+	return "Task digest for " + d.Date.Format("Mon, 02 Jan 2006")
+}
+
+// DigestText render a digest as plain text
+func DigestText(d taskmanager.Digest) string {
+	var b strings.Builder
+	b.WriteString(DigestSubject(d) + "\n")
+	for _, section := range digestSections(d) {
+		fmt.Fprintf(&b, "\n%s (%d)\n", section.Title, len(section.Tasks))
+		if len(section.Tasks) == 0 {
+			b.WriteString("  None\n")
+		}
+		for _, task := range section.Tasks {
+			line := fmt.Sprintf("  %d  %s", task.Id, task.Description)
+			if when := section.When(task); when != "" {
+				line += "  " + when
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// DigestHTML render a digest as an html page
+func DigestHTML(d taskmanager.Digest) (string, error) {
+	var b bytes.Buffer
+	err := digestTemplate.Execute(&b, struct {
+		Subject  string
+		Sections []digestSection
+	}{DigestSubject(d), digestSections(d)})
+	return b.String(), err
+}
+
+// the sections of a digest in the order they are shown
+func digestSections(d taskmanager.Digest) []digestSection {
+	loc := d.Date.Location()
+	today := func(at time.Time) bool {
+		return at.In(loc).Format("2006-01-02") == d.Date.Format("2006-01-02")
+	}
+	format := func(at *time.Time, layout string) string {
+		if at == nil {
+			return ""
+		}
+		return at.In(loc).Format(layout)
+	}
+	return []digestSection{
+		{Title: "Overdue", Tasks: d.Overdue, When: func(task taskmanager.Task) string {
+			return "due " + format(task.Due, "Mon 01/02 15:04")
+		}},
+		{Title: "Due today", Tasks: d.DueToday, When: func(task taskmanager.Task) string {
+			if task.Due != nil && today(*task.Due) {
+				return "due " + format(task.Due, "15:04")
+			}
+			for _, alarm := range task.Alarms() {
+				if today(alarm.At) {
+					return "reminder " + format(&alarm.At, "15:04")
+				}
+			}
+			return ""
+		}},
+		{Title: "New", Tasks: d.New, When: func(task taskmanager.Task) string {
+			return "added " + format(&task.Created, "Mon 15:04")
+		}},
+		{Title: "Completed yesterday", Tasks: d.CompletedYesterday, When: func(task taskmanager.Task) string {
+			return "done " + format(task.Completed, "15:04")
+		}},
+	}
+}
@@ -0,0 +1,90 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+// Email sends the message through a smtp server, by default a local one
+// that relays without authentication
+type Email struct {
+	Addr string
+	From string
+	To   []string
+	// Auth is used when the server requires it, optional
+	Auth smtp.Auth
+}
+
+// Notify send the message as a plain text mail
+func (e *Email) Notify(msg Message) error {
+	var b bytes.Buffer
+	e.header(&b, msg.Title+": "+msg.Task.Description)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	if err := writeQuotedPrintable(&b, msg.Body+"\n"); err != nil {
+		return err
+	}
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, b.Bytes())
+}
+
+// SendDigest send the daily digest with a plain text and an html body
+func (e *Email) SendDigest(d taskmanager.Digest) error {
+	html, err := DigestHTML(d)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", DigestText(d)},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	e.header(&b, DigestSubject(d))
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	b.Write(body.Bytes())
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, b.Bytes())
+}
+
+// write the headers shared by every mail
+func (e *Email) header(b *bytes.Buffer, subject string) {
+	fmt.Fprintf(b, "From: %s\r\n", e.From)
+	fmt.Fprintf(b, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+}
+
+// write a body as quoted-printable, it keeps the lines short and 7 bit
+// whatever the text, and ends them with CRLF
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
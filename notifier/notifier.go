@@ -0,0 +1,183 @@
+// Package notifier delivers task reminders through pluggable backends,
+// several of them can fire at once
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+type (
+	// Message is a notification about a task
+	Message struct {
+		Title string
+		Body  string
+		Task  taskmanager.Task
+	}
+
+	// Notifier delivers a message through a single backend
+	Notifier interface {
+		Notify(msg Message) error
+	}
+
+	// Flusher is implemented by notifiers that keep failed deliveries to
+	// send them later
+	Flusher interface {
+		Flush() error
+	}
+
+	// Multi fires every notifier, it fails only when none of them delivered
+	Multi struct {
+		Notifiers []Notifier
+		// Fallback is notified when none of the notifiers delivered, optional
+		Fallback Notifier
+		// Error receive the failures of single notifiers, optional
+		Error func(err error)
+	}
+)
+
+// Notify deliver the message to every notifier, a queued delivery is
+// reported but counts as delivered
+func (m *Multi) Notify(msg Message) error {
+	var failures []string
+	for _, n := range m.Notifiers {
+		err := n.Notify(msg)
+		if err == nil {
+			continue
+		}
+		if m.Error != nil {
+			m.Error(err)
+		}
+		if !errors.Is(err, ErrQueued) {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 && len(failures) == len(m.Notifiers) {
+		if m.Fallback != nil {
+			err := m.Fallback.Notify(msg)
+			if err == nil {
+				return nil
+			}
+			failures = append(failures, err.Error())
+		}
+		return fmt.Errorf("no notifier delivered: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Flush send the kept deliveries of every notifier that has some
+func (m *Multi) Flush() {
+	for _, n := range m.Notifiers {
+		if f, ok := n.(Flusher); ok {
+			if err := f.Flush(); err != nil && m.Error != nil {
+				m.Error(err)
+			}
+		}
+	}
+}
+
+// FromEnv return the notifiers configured by the environment. TASK_NOTIFIERS
+// is a comma separated list of desktop, terminal, command, webhook and email.
+// When it is empty the desktop is used and the terminal when no desktop
+// notification can be shown, like on a headless machine. The backends read
+// their own settings:
+//
+//	TASK_NOTIFY_COMMAND   shell command run by the command notifier
+//	TASK_WEBHOOK_URL      url the webhook notifier posts to
+//	TASK_WEBHOOK_TEMPLATE json body template of the webhook, @path reads it from a file
+//	TASK_WEBHOOK_SECRET   HMAC-SHA256 key signing the webhook body
+//	TASK_WEBHOOK_QUEUE    file keeping failed webhooks, ~/.task.webhook.json by default
+//	TASK_SMTP_ADDR        smtp server of the email notifier, localhost:25 by default
+//	TASK_SMTP_FROM        sender address of the email notifier
+//	TASK_SMTP_TO          comma separated recipients of the email notifier
+func FromEnv() (*Multi, error) {
+	m := &Multi{}
+	names := os.Getenv("TASK_NOTIFIERS")
+	if names == "" {
+		names = "desktop"
+		m.Fallback = &Terminal{Out: os.Stdout, Bell: true}
+	}
+	for _, name := range strings.Split(names, ",") {
+		n, err := New(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		m.Notifiers = append(m.Notifiers, n)
+	}
+	return m, nil
+}
+
+// New return a single notifier configured by the environment
+func New(name string) (Notifier, error) {
+	switch strings.ToLower(name) {
+	case "desktop":
+		return &Desktop{AppName: "Terminal Task"}, nil
+	case "terminal":
+		return &Terminal{Out: os.Stdout, Bell: true}, nil
+	case "command":
+		if os.Getenv("TASK_NOTIFY_COMMAND") == "" {
+			return nil, fmt.Errorf("command notifier needs TASK_NOTIFY_COMMAND")
+		}
+		return &Command{Command: os.Getenv("TASK_NOTIFY_COMMAND")}, nil
+	case "webhook":
+		if os.Getenv("TASK_WEBHOOK_URL") == "" {
+			return nil, fmt.Errorf("webhook notifier needs TASK_WEBHOOK_URL")
+		}
+		return webhookFromEnv()
+	case "email":
+		return EmailFromEnv()
+	}
+	return nil, fmt.Errorf("unknown notifier %q", name)
+}
+
+// EmailFromEnv return the email notifier configured by the environment,
+// the digest is sent with it too
+func EmailFromEnv() (*Email, error) {
+	email := &Email{Addr: os.Getenv("TASK_SMTP_ADDR"), From: os.Getenv("TASK_SMTP_FROM")}
+	if email.Addr == "" {
+		email.Addr = "localhost:25"
+	}
+	for _, to := range strings.Split(os.Getenv("TASK_SMTP_TO"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			email.To = append(email.To, to)
+		}
+	}
+	if email.From == "" || len(email.To) == 0 {
+		return nil, fmt.Errorf("email notifier needs TASK_SMTP_FROM and TASK_SMTP_TO")
+	}
+	return email, nil
+}
+
+// webhook notifier configured by the environment
+func webhookFromEnv() (*Webhook, error) {
+	w := &Webhook{
+		URL:      os.Getenv("TASK_WEBHOOK_URL"),
+		Template: os.Getenv("TASK_WEBHOOK_TEMPLATE"),
+		Secret:   os.Getenv("TASK_WEBHOOK_SECRET"),
+		Backoff:  time.Second,
+		Queue:    os.Getenv("TASK_WEBHOOK_QUEUE"),
+	}
+	if strings.HasPrefix(w.Template, "@") {
+		data, err := ioutil.ReadFile(w.Template[1:])
+		if err != nil {
+			return nil, fmt.Errorf("can not read the webhook template: %v", err)
+		}
+		w.Template = string(data)
+	}
+	if w.Queue == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("can not find the home directory: %v", err)
+		}
+		w.Queue = filepath.Join(usr.HomeDir, ".task.webhook.json")
+	}
+	return w, nil
+}
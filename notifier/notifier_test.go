@@ -0,0 +1,403 @@
+package notifier
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+var message = Message{
+	Title: "Task Reminder!",
+	Body:  "Meeting with John",
+	Task:  taskmanager.Task{Id: 3, UID: "c", Description: "Meeting with John"},
+}
+
+// notifierFunc turns a function into a Notifier
+type notifierFunc func(msg Message) error
+
+func (f notifierFunc) Notify(msg Message) error { return f(msg) }
+
+// smtpStub accepts mails on a local port and sends every received mail to the channel
+func smtpStub(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mails := make(chan string, 10)
+	go func() {
+		defer l.Close()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				conn.Write([]byte("220 localhost ESMTP\r\n"))
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "DATA"):
+						conn.Write([]byte("354 go ahead\r\n"))
+						var mail bytes.Buffer
+						for {
+							line, err := r.ReadString('\n')
+							if err != nil || line == ".\r\n" {
+								break
+							}
+							mail.WriteString(line)
+						}
+						mails <- mail.String()
+						conn.Write([]byte("250 queued\r\n"))
+					case strings.HasPrefix(cmd, "QUIT"):
+						conn.Write([]byte("221 bye\r\n"))
+						return
+					default:
+						conn.Write([]byte("250 OK\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String(), mails
+}
+
+func TestMulti(t *testing.T) {
+	var delivered, failed int
+	fail := notifierFunc(func(Message) error { return errors.New("offline") })
+	ok := notifierFunc(func(Message) error { delivered++; return nil })
+	m := &Multi{Notifiers: []Notifier{fail, ok, ok}, Error: func(error) { failed++ }}
+	if err := m.Notify(message); err != nil || delivered != 2 || failed != 1 {
+		t.Error("Every notifier must fire and a partial failure must not fail", err)
+	}
+	m = &Multi{Notifiers: []Notifier{fail, fail}}
+	if err := m.Notify(message); err == nil {
+		t.Error("Failure of every notifier must fail")
+	}
+	m = &Multi{Notifiers: []Notifier{fail}, Fallback: ok}
+	if err := m.Notify(message); err != nil || delivered != 3 {
+		t.Error("Fallback must deliver when every notifier failed", err)
+	}
+	failed = 0
+	queued := notifierFunc(func(Message) error { return fmt.Errorf("%w: offline", ErrQueued) })
+	m = &Multi{Notifiers: []Notifier{queued}, Error: func(error) { failed++ }}
+	if err := m.Notify(message); err != nil || failed != 1 {
+		t.Error("A queued delivery must be reported and count as delivered", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	defer os.Unsetenv("TASK_NOTIFIERS")
+	defer os.Unsetenv("TASK_WEBHOOK_URL")
+	os.Setenv("TASK_NOTIFIERS", "terminal, webhook")
+	os.Setenv("TASK_WEBHOOK_URL", "http://localhost/hook")
+	m, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Notifiers) != 2 {
+		t.Fatal("Failed to configure several notifiers")
+	}
+	if _, ok := m.Notifiers[1].(*Webhook); !ok {
+		t.Error("Failed to configure the webhook notifier")
+	}
+	if m.Fallback != nil {
+		t.Error("Chosen notifiers must not fall back")
+	}
+	os.Unsetenv("TASK_NOTIFIERS")
+	if m, err := FromEnv(); err != nil || m.Fallback == nil {
+		t.Error("Default desktop notifier must fall back to the terminal", err)
+	}
+	for _, names := range []string{"pager", "email", "command"} {
+		os.Setenv("TASK_NOTIFIERS", names)
+		if _, err := FromEnv(); err == nil {
+			t.Errorf("Notifiers %q must not be configured", names)
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	var out bytes.Buffer
+	if err := (&Terminal{Out: &out, Bell: true}).Notify(message); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "\a") || !strings.HasSuffix(out.String(), "Task Reminder!: Meeting with John\n") {
+		t.Error("Unexpected terminal notification", out.String())
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a posix shell")
+	}
+	dir, err := ioutil.TempDir("", "notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	c := &Command{Command: `echo "$TASK_ID $TASK_TITLE" > ` + out}
+	if err := c.Notify(message); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(out); string(data) != "3 Task Reminder!\n" {
+		t.Error("Command did not get the message", string(data))
+	}
+	if err := (&Command{Command: "exit 3"}).Notify(message); err == nil {
+		t.Error("Failed command must fail")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Error("Webhook must post json")
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+	if err := (&Webhook{URL: server.URL}).Notify(message); err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != message.Title || got.Task.UID != "c" {
+		t.Error("Unexpected webhook payload", got)
+	}
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	if err := (&Webhook{URL: server.URL}).Notify(message); err == nil {
+		t.Error("Non 2xx answer must fail")
+	}
+}
+
+func TestWebhook_template(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+	w := &Webhook{URL: server.URL, Template: `{"text": {{json .Task.Description}}, "id": {{.Task.Id}}}`}
+	if err := w.Notify(message); err != nil {
+		t.Fatal(err)
+	}
+	if got["text"] != "Meeting with John" || got["id"] != 3.0 {
+		t.Error("Unexpected templated payload", got)
+	}
+	w.Template = `{"text": {{.Task.Description}}}`
+	if err := w.Notify(message); err == nil {
+		t.Error("A template that does not render json must fail")
+	}
+}
+
+func TestWebhook_signature(t *testing.T) {
+	valid := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		valid = hmac.Equal([]byte(r.Header.Get(signatureHeader)), []byte(Sign("s3cret", body)))
+	}))
+	defer server.Close()
+	if err := (&Webhook{URL: server.URL, Secret: "s3cret"}).Notify(message); err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("Webhook body must be signed with the secret")
+	}
+}
+
+func TestWebhook_retry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var attempts int
+	ids := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		ids[r.Header.Get(deliveryHeader)] = true
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	w := &Webhook{URL: server.URL, Backoff: time.Millisecond, Queue: filepath.Join(dir, "webhook.json")}
+	if err := w.Notify(message); !errors.Is(err, ErrQueued) || attempts != 1 {
+		t.Fatal("Failed webhook must be queued without retrying right away", attempts, err)
+	}
+	for i := 0; i < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		w.Flush()
+	}
+	if attempts != 3 || len(ids) != 1 {
+		t.Error("Failed webhook must be retried with the same delivery id", attempts, ids)
+	}
+}
+
+func TestWebhook_queue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	queue := filepath.Join(dir, "webhook.json")
+	var delivered []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	w := &Webhook{URL: server.URL, Backoff: 50 * time.Millisecond, Queue: queue}
+	if err := w.Notify(message); !errors.Is(err, ErrQueued) {
+		t.Fatal("Failed delivery must be queued", err)
+	}
+	if _, err := os.Stat(queue); err != nil {
+		t.Fatal("Failed delivery must be persisted", err)
+	}
+	// a restarted daemon sends the queue once it is due
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		delivered = append(delivered, string(body))
+	})
+	w = &Webhook{URL: server.URL, Queue: queue}
+	if err := w.Flush(); err != nil || len(delivered) != 0 {
+		t.Error("Queued delivery must wait for its backoff", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := w.Flush(); err != nil || len(delivered) != 1 || !strings.Contains(delivered[0], "Meeting with John") {
+		t.Error("Failed to send the queued delivery", err, delivered)
+	}
+	if _, err := os.Stat(queue); !os.IsNotExist(err) {
+		t.Error("Sent queue must be removed", err)
+	}
+}
+
+func TestWebhook_drop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	queue := filepath.Join(dir, "webhook.json")
+	var attempts int
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	w := &Webhook{URL: server.URL, Backoff: time.Millisecond, Queue: queue, MaxAttempts: 3}
+	if err := w.Notify(message); err == nil || errors.Is(err, ErrQueued) {
+		t.Error("Rejected delivery must not be queued", err)
+	}
+	if _, err := os.Stat(queue); !os.IsNotExist(err) {
+		t.Error("Rejected delivery must not be persisted", err)
+	}
+	// a delivery that keeps failing is dropped after the last attempt
+	status = http.StatusTooManyRequests
+	attempts = 0
+	if err := w.Notify(message); !errors.Is(err, ErrQueued) {
+		t.Fatal("Rate limited delivery must be queued", err)
+	}
+	for i := 0; i < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		err = w.Flush()
+	}
+	if attempts != 3 || err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Error("Delivery must be dropped after its last attempt", attempts, err)
+	}
+	if _, err := os.Stat(queue); !os.IsNotExist(err) {
+		t.Error("Dropped delivery must leave the queue", err)
+	}
+	// a queued delivery the receiver rejects later is dropped as well
+	status = http.StatusServiceUnavailable
+	w.Notify(message)
+	status = http.StatusNotFound
+	time.Sleep(10 * time.Millisecond)
+	if err := w.Flush(); err == nil || !strings.Contains(err.Error(), "dropped delivery") {
+		t.Error("Rejected queued delivery must be dropped", err)
+	}
+	if _, err := os.Stat(queue); !os.IsNotExist(err) {
+		t.Error("Rejected delivery must leave the queue", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	addr, mails := smtpStub(t)
+	e := &Email{Addr: addr, From: "task@localhost", To: []string{"john@localhost"}}
+	if err := e.Notify(message); err != nil {
+		t.Fatal(err)
+	}
+	mail := <-mails
+	if !strings.Contains(mail, "To: john@localhost") || !strings.Contains(mail, "Subject: Task Reminder!: Meeting with John") ||
+		!strings.Contains(mail, "Content-Transfer-Encoding: quoted-printable") {
+		t.Error("Unexpected mail", mail)
+	}
+	// a long line of non ascii text is encoded to short 7 bit lines
+	msg := message
+	msg.Body = strings.Repeat("Réunion avec John ", 10)
+	if err := e.Notify(msg); err != nil {
+		t.Fatal(err)
+	}
+	mail = <-mails
+	body := mail[strings.Index(mail, "\r\n\r\n")+4:]
+	decoded, err := ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	if err != nil || !strings.Contains(string(decoded), msg.Body) {
+		t.Error("Failed to decode the mail body", string(decoded), err)
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 76 || strings.ContainsAny(line, "é") {
+			t.Error("Mail body must be quoted-printable", line)
+		}
+	}
+}
+
+func TestEmail_SendDigest(t *testing.T) {
+	addr, mails := smtpStub(t)
+	now := time.Date(2017, 7, 21, 12, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Hour)
+	d := taskmanager.Digest{
+		Date:    now.Truncate(24 * time.Hour),
+		Overdue: taskmanager.Tasks{{Id: 4, Description: "Submit <report>", Due: &due}},
+	}
+	text := DigestText(d)
+	if !strings.Contains(text, "Overdue (1)\n  4  Submit <report>  due Fri 07/21 11:00") || !strings.Contains(text, "New (0)\n  None") {
+		t.Error("Unexpected text digest", text)
+	}
+	e := &Email{Addr: addr, From: "task@localhost", To: []string{"john@localhost"}}
+	if err := e.SendDigest(d); err != nil {
+		t.Fatal(err)
+	}
+	mail := <-mails
+	for _, part := range []string{
+		"Subject: Task digest for Fri, 21 Jul 2017",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"Submit &lt;report&gt;",
+	} {
+		if !strings.Contains(mail, part) {
+			t.Errorf("Digest mail misses %q\n%s", part, mail)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Terminal writes the message to a terminal, for headless machines
+type Terminal struct {
+	Out io.Writer
+	// Bell rings the terminal bell before the message
+	Bell bool
+}
+
+// Notify write the message as a single line
+func (t *Terminal) Notify(msg Message) error {
+	bell := ""
+	if t.Bell {
+		bell = "\a"
+	}
+	_, err := fmt.Fprintf(t.Out, "%s[%s] %s: %s\n", bell, time.Now().Format("2006-01-02 15:04"), msg.Title, msg.Body)
+	return err
+}
@@ -0,0 +1,300 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+const (
+	// signatureHeader carries the hex HMAC-SHA256 of the body, prefixed by "sha256="
+	signatureHeader = "X-Task-Signature-256"
+	// deliveryHeader carries an id that stays the same over retries, so receivers can drop duplicates
+	deliveryHeader = "X-Task-Delivery"
+	// maxBackoff caps the wait between two retries of a queued delivery
+	maxBackoff = time.Hour
+	// defaultMaxAttempts is the number of tries after which a queued delivery is dropped
+	defaultMaxAttempts = 10
+)
+
+// ErrQueued is returned when a delivery failed and was persisted to the
+// queue, it is sent again by Flush
+var ErrQueued = errors.New("webhook delivery queued")
+
+type (
+	// Webhook posts the message as json to a url. A failed post is persisted
+	// to the Queue file and retried by Flush with exponential backoff, also
+	// after a restart, so Notify never waits for the retries.
+	Webhook struct {
+		URL string
+		// Template renders the json body from the Message with text/template,
+		// {{json .Task.Description}} quotes a value. The default payload when empty.
+		Template string
+		// Secret signs the body with HMAC-SHA256, unsigned when empty
+		Secret string
+		// Backoff is the wait before the first retry, it doubles on each one
+		// up to an hour, a second when zero
+		Backoff time.Duration
+		// Queue is the file failed deliveries are persisted to, they are
+		// dropped when empty
+		Queue string
+		// MaxAttempts is the number of tries after which a queued delivery
+		// is dropped, 10 when zero
+		MaxAttempts int
+		// Client sends the request, a client with a 10 seconds timeout when nil
+		Client *http.Client
+
+		mu sync.Mutex
+	}
+
+	// webhookPayload is the default json body of a webhook
+	webhookPayload struct {
+		Title string           `json:"title"`
+		Body  string           `json:"body"`
+		Task  taskmanager.Task `json:"task"`
+	}
+
+	// statusError is a delivery the receiver answered with a non 2xx status
+	statusError struct {
+		URL    string
+		Status string
+		Code   int
+	}
+
+	// delivery is a rendered webhook waiting in the queue
+	delivery struct {
+		ID       string    `json:"id"`
+		URL      string    `json:"url"`
+		Body     string    `json:"body"`
+		Attempts int       `json:"attempts"`
+		Next     time.Time `json:"next"`
+	}
+)
+
+// Notify post the message once, a failed delivery is queued for Flush
+func (w *Webhook) Notify(msg Message) error {
+	body, err := w.render(msg)
+	if err != nil {
+		return err
+	}
+	d := delivery{ID: deliveryID(), URL: w.URL, Body: body, Attempts: 1}
+	err = w.post(d)
+	if err == nil || w.Queue == "" || !retryable(err) {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	queue, qerr := w.load()
+	if qerr != nil {
+		return fmt.Errorf("%v, and it can not be queued: %v", err, qerr)
+	}
+	d.Next = time.Now().Add(w.backoff(d.Attempts))
+	if qerr := w.store(append(queue, d)); qerr != nil {
+		return fmt.Errorf("%v, and it can not be queued: %v", err, qerr)
+	}
+	return fmt.Errorf("%w: %v", ErrQueued, err)
+}
+
+// Flush send the queued deliveries that are due, the ones that fail again
+// wait twice as long before the next try. A delivery the receiver rejects
+// or that failed MaxAttempts times is dropped and reported in the error.
+func (w *Webhook) Flush() error {
+	if w.Queue == "" {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	queue, err := w.load()
+	if err != nil || len(queue) == 0 {
+		return err
+	}
+	var pending []delivery
+	var failures []string
+	for _, d := range queue {
+		if time.Now().Before(d.Next) {
+			pending = append(pending, d)
+			continue
+		}
+		d.Attempts++
+		err := w.post(d)
+		switch {
+		case err == nil:
+		case !retryable(err):
+			failures = append(failures, fmt.Sprintf("dropped delivery %s: %v", d.ID, err))
+		case d.Attempts >= w.maxAttempts():
+			failures = append(failures, fmt.Sprintf("dropped delivery %s after %d attempts: %v", d.ID, d.Attempts, err))
+		default:
+			d.Next = time.Now().Add(w.backoff(d.Attempts))
+			pending = append(pending, d)
+			failures = append(failures, err.Error())
+		}
+	}
+	if err := w.store(pending); err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d queued webhook deliveries failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// render the body of a message, it must be valid json
+func (w *Webhook) render(msg Message) (string, error) {
+	if w.Template == "" {
+		body, err := json.Marshal(webhookPayload{Title: msg.Title, Body: msg.Body, Task: msg.Task})
+		return string(body), err
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(w.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook template: %v", err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, msg); err != nil {
+		return "", fmt.Errorf("invalid webhook template: %v", err)
+	}
+	if !json.Valid(b.Bytes()) {
+		return "", fmt.Errorf("webhook template does not render json: %s", b.String())
+	}
+	return b.String(), nil
+}
+
+// post a delivery once, any status other than 2xx is a failure
+func (w *Webhook) post(d delivery) error {
+	req, err := http.NewRequest(http.MethodPost, d.URL, strings.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(deliveryHeader, d.ID)
+	if w.Secret != "" {
+		req.Header.Set(signatureHeader, Sign(w.Secret, []byte(d.Body)))
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{URL: d.URL, Status: resp.Status, Code: resp.StatusCode}
+	}
+	return nil
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook %s answered %s", e.URL, e.Status)
+}
+
+// retryable tells if a failed delivery may succeed later, a 4xx answer other
+// than a timeout or rate limit is a rejection that is never retried
+func retryable(err error) bool {
+	var status *statusError
+	if !errors.As(err, &status) || status.Code < 400 || status.Code > 499 {
+		return true
+	}
+	return status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests
+}
+
+// number of tries after which a queued delivery is dropped
+func (w *Webhook) maxAttempts() int {
+	if w.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return w.MaxAttempts
+}
+
+// wait before the next try of a queued delivery
+func (w *Webhook) backoff(attempts int) time.Duration {
+	backoff := w.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// read the queue file, a missing file is an empty queue
+func (w *Webhook) load() ([]delivery, error) {
+	data, err := ioutil.ReadFile(w.Queue)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var queue []delivery
+	return queue, json.Unmarshal(data, &queue)
+}
+
+// replace the queue file, an empty queue removes it
+func (w *Webhook) store(queue []delivery) error {
+	if len(queue) == 0 {
+		if err := os.Remove(w.Queue); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(w.Queue), filepath.Base(w.Queue)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), w.Queue)
+}
+
+// Sign return the signature header value of a body, receivers compute it
+// with the shared secret and compare it with hmac.Equal
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// random id of a delivery
+func deliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+// dialTimeout keeps the cli quick when no listener answers
+const dialTimeout = time.Second
+
+// ErrRunning is returned by Serve when another listener owns the socket
+var ErrRunning = errors.New("a reminder listener is already running")
+
+type (
+	// Control is the JSON-RPC receiver of the control socket, its methods
+	// are called as "Control.Ping", "Control.List" and so on
+	Control struct {
+		scheduler *taskmanager.Scheduler
+		shutdown  func()
+		started   time.Time
+		once      sync.Once
+	}
+
+	// PingReply tells about the running listener
+	PingReply struct {
+		PID       int       `json:"pid"`
+		Started   time.Time `json:"started"`
+		Paused    bool      `json:"paused"`
+		Scheduled int       `json:"scheduled"`
+	}
+
+	// Client talks to the listener over its control socket
+	Client struct {
+		rpc *rpc.Client
+	}
+
+	// Empty is the argument of the methods that take none
+	Empty struct{}
+)
+
+// SocketPath return the path of the control socket, TASK_SOCKET when set,
+// else task.sock in XDG_RUNTIME_DIR or .task.sock in the home directory
+func SocketPath() (string, error) {
+	if path := os.Getenv("TASK_SOCKET"); path != "" {
+		return path, nil
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "task.sock"), nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("can not find the home directory: %v", err)
+	}
+	return filepath.Join(usr.HomeDir, ".task.sock"), nil
+}
+
+// Serve answer the control requests on a unix socket at path until the
+// returned listener is closed, shutdown is called once on request. A stale
+// socket left by a crashed listener is replaced.
+func Serve(path string, scheduler *taskmanager.Scheduler, shutdown func()) (net.Listener, error) {
+	if c, err := Dial(path); err == nil {
+		c.Close()
+		return nil, ErrRunning
+	}
+	os.Remove(path)
+	// only the user may control the listener
+	l, err := listenPrivate(path)
+	if err != nil {
+		return nil, err
+	}
+	server := rpc.NewServer()
+	control := &Control{scheduler: scheduler, shutdown: shutdown, started: time.Now()}
+	if err := server.Register(control); err != nil {
+		l.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return l, nil
+}
+
+// Ping report the state of the listener
+func (c *Control) Ping(_ Empty, reply *PingReply) error {
+	*reply = PingReply{
+		PID:       os.Getpid(),
+		Started:   c.started,
+		Paused:    c.scheduler.Paused(),
+		Scheduled: len(c.scheduler.Scheduled()),
+	}
+	return nil
+}
+
+// List return the scheduled reminders, the next one first
+func (c *Control) List(_ Empty, reply *[]taskmanager.Alarm) error {
+	*reply = c.scheduler.Scheduled()
+	return nil
+}
+
+// Reload read the tasks again and reschedule
+func (c *Control) Reload(_ Empty, _ *Empty) error {
+	c.scheduler.Reload()
+	return nil
+}
+
+// Pause hold the notifications back until Resume
+func (c *Control) Pause(_ Empty, _ *Empty) error {
+	c.scheduler.Pause()
+	return nil
+}
+
+// Resume send the notifications again, the held ones first
+func (c *Control) Resume(_ Empty, _ *Empty) error {
+	c.scheduler.Resume()
+	return nil
+}
+
+// Shutdown stop the listener, a service manager does not restart it
+func (c *Control) Shutdown(_ Empty, _ *Empty) error {
+	c.once.Do(c.shutdown)
+	return nil
+}
+
+// Dial connect to the listener, it fails quickly when none is running
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(10 * dialTimeout))
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+// Ping ask the listener about its state
+func (c *Client) Ping() (PingReply, error) {
+	var reply PingReply
+	err := c.rpc.Call("Control.Ping", Empty{}, &reply)
+	return reply, err
+}
+
+// List return the scheduled reminders of the listener
+func (c *Client) List() ([]taskmanager.Alarm, error) {
+	var alarms []taskmanager.Alarm
+	err := c.rpc.Call("Control.List", Empty{}, &alarms)
+	return alarms, err
+}
+
+// Reload make the listener reschedule from the database
+func (c *Client) Reload() error {
+	return c.rpc.Call("Control.Reload", Empty{}, &Empty{})
+}
+
+// Pause hold the notifications of the listener back
+func (c *Client) Pause() error {
+	return c.rpc.Call("Control.Pause", Empty{}, &Empty{})
+}
+
+// Resume let the listener notify again
+func (c *Client) Resume() error {
+	return c.rpc.Call("Control.Resume", Empty{}, &Empty{})
+}
+
+// Shutdown stop the listener
+func (c *Client) Shutdown() error {
+	return c.rpc.Call("Control.Shutdown", Empty{}, &Empty{})
+}
+
+// Close the connection
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
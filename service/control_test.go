@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thedevsaddam/task/taskmanager"
+)
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	remindAt := time.Now().Add(time.Hour)
+	storage := taskmanager.NewFileStorage(filepath.Join(dir, "tasks.json"))
+	storage.Save(taskmanager.Tasks{{Id: 1, Description: "Meeting with John", RemindAt: &remindAt}})
+	scheduler := taskmanager.NewScheduler(storage, func(taskmanager.Alarm) error { return nil })
+	done := make(chan struct{})
+	stopped := make(chan error)
+	go func() { stopped <- scheduler.Run(done) }()
+
+	socket := filepath.Join(dir, "task.sock")
+	l, err := Serve(socket, scheduler, func() { close(done) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if fi, err := os.Stat(socket); runtime.GOOS != "windows" && (err != nil || fi.Mode().Perm()&0077 != 0) {
+		t.Error("Only the user may connect to the socket", fi.Mode(), err)
+	}
+	if _, err := Serve(socket, scheduler, func() {}); !errors.Is(err, ErrRunning) {
+		t.Error("Only one listener may own the socket", err)
+	}
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := c.Ping()
+	if err != nil || reply.PID != os.Getpid() || !reply.Paused {
+		t.Error("Unexpected ping reply", reply, err)
+	}
+	if err := c.Resume(); err != nil || scheduler.Paused() {
+		t.Error("Failed to resume the listener", err)
+	}
+	// a reminder added by the cli is scheduled once the listener reloads
+	later := remindAt.Add(time.Hour)
+	storage.Save(taskmanager.Tasks{
+		{Id: 1, Description: "Meeting with John", RemindAt: &remindAt},
+		{Id: 2, Description: "Call mom", RemindAt: &later},
+	})
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	var alarms []taskmanager.Alarm
+	for i := 0; i < 100 && len(alarms) != 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if alarms, err = c.List(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(alarms) != 2 || alarms[0].Task.Id != 1 || alarms[1].Task.Id != 2 {
+		t.Error("Failed to list the scheduled reminders", alarms)
+	}
+	if err := c.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listener did not shut down")
+	}
+	l.Close()
+	files, _ := ioutil.ReadDir(dir)
+	for _, fi := range files {
+		if fi.Name() == "task.sock" || strings.HasPrefix(fi.Name(), ".task-socket") {
+			t.Error("Closed listener must remove its socket", fi.Name())
+		}
+	}
+}
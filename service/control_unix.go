@@ -0,0 +1,51 @@
+//go:build !windows
+// +build !windows
+
+package service
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// socketListener removes its socket file once it is closed
+type socketListener struct {
+	net.Listener
+	path string
+}
+
+// listen on a unix socket only the user can connect to. It is bound in a
+// private directory and moved into place once it has its mode, so others can
+// never reach it, without touching the umask of the whole process.
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".task-socket")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// the socket is moved away, so it is removed by its new path
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &socketListener{Listener: l, path: path}, nil
+}
+
+// Close stop listening and remove the socket file
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+	os.Remove(l.path)
+	return err
+}
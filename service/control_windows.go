@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package service
+
+import "net"
+
+// listen on a unix socket, windows has no umask and keeps it in the
+// directory permissions of the user
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,191 @@
+// Package service installs the reminder listener as a user service, a
+// systemd unit on linux, a launchd agent on macOS and an autostart entry
+// elsewhere
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-autostart"
+)
+
+// passEnv are the variables besides TASK_* the listener needs to reach the desktop
+var passEnv = []string{"DISPLAY", "WAYLAND_DISPLAY", "DBUS_SESSION_BUS_ADDRESS"}
+
+// legacy is the autostart entry older versions registered with a fixed
+// /usr/local/bin/task, it is removed when the service is installed
+var legacy = autostart.App{
+	Name:        "thedevsaddam_terminal_task",
+	DisplayName: "Task",
+	Exec:        []string{"/usr/local/bin/task", "listen-reminder-queue"},
+}
+
+type (
+	// Service describes the listener process the service manager runs
+	Service struct {
+		// Name names the systemd unit, the launchd label and the autostart entry
+		Name        string
+		Description string
+		// Exec is the command line, the first item is an absolute path
+		Exec []string
+		// Env is the environment of the process
+		Env map[string]string
+	}
+
+	// Status is what the service manager reports about the service
+	Status struct {
+		// Installed tells whether the service is registered
+		Installed bool
+		// Active tells whether the listener process is running
+		Active bool
+		// Path is the unit, plist or autostart file
+		Path string
+		// Detail is the state reported by the service manager
+		Detail string
+	}
+)
+
+// New return the service running this executable with the arguments, the
+// TASK_* variables of the current environment are kept so the listener
+// uses the same database and notifiers
+func New(args ...string) (*Service, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("can not find the task executable: %v", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	s := &Service{
+		Name:        "task-reminder",
+		Description: "Terminal Task reminder listener",
+		Exec:        append([]string{exe}, args...),
+		Env:         map[string]string{},
+	}
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && (strings.HasPrefix(parts[0], "TASK_") || contains(passEnv, parts[0])) {
+			s.Env[parts[0]] = parts[1]
+		}
+	}
+	// the service does not start in the current directory
+	if path := s.Env["TASK_DB_FILE_PATH"]; path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			s.Env["TASK_DB_FILE_PATH"] = abs
+		}
+	}
+	return s, nil
+}
+
+// SystemdUnit render the systemd user unit, the listener is restarted when it fails
+func (s *Service) SystemdUnit() string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=%s\n", s.Description)
+	b.WriteString("After=network-online.target\n\n")
+	b.WriteString("[Service]\n")
+	b.WriteString("Type=simple\n")
+	exec := make([]string, len(s.Exec))
+	for i, arg := range s.Exec {
+		exec[i] = strings.Replace(systemdQuote(arg), "$", "$$", -1)
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(exec, " "))
+	for _, key := range s.envKeys() {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(key+"="+s.Env[key]))
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=10\n\n")
+	b.WriteString("[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+// LaunchdPlist render the launchd agent, it is started at login and again
+// when it exits with a failure, the output goes to logs
+func (s *Service) LaunchdPlist(logs string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(s.Label()))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range s.Exec {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	if len(s.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, key := range s.envKeys() {
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlEscape(key), xmlEscape(s.Env[key]))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(logs))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(logs))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// Label return the launchd label of the service
+func (s *Service) Label() string {
+	return "com.thedevsaddam." + s.Name
+}
+
+// the environment keys in a stable order
+func (s *Service) envKeys() []string {
+	keys := make([]string, 0, len(s.Env))
+	for key := range s.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// write a service file readable by the user only, it may hold secrets
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(content), 0600)
+}
+
+// remove the autostart entry of older versions so only one listener runs
+func removeLegacy() error {
+	if legacy.IsEnabled() {
+		return legacy.Disable()
+	}
+	return nil
+}
+
+// quote a systemd value, % starts a specifier
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(s)
+	return `"` + s + `"`
+}
+
+// escape a plist string
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// tell whether a list has the item
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
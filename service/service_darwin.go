@@ -0,0 +1,123 @@
+//go:build darwin
+// +build darwin
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pidPattern finds the process of a running agent in the output of launchctl list
+var pidPattern = regexp.MustCompile(`"PID" = (\d+);`)
+
+// Install write the launchd agent and load it, which starts the listener
+func (s *Service) Install() error {
+	path, err := s.plistPath()
+	if err != nil {
+		return err
+	}
+	logs, err := s.logPath()
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, s.LaunchdPlist(logs)); err != nil {
+		return err
+	}
+	if err := removeLegacy(); err != nil {
+		return err
+	}
+	// an agent loaded before keeps its old definition until it is unloaded
+	launchctl("unload", path)
+	_, err = launchctl("load", "-w", path)
+	return err
+}
+
+// Uninstall unload the agent and remove its plist
+func (s *Service) Uninstall() error {
+	path, err := s.plistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return removeLegacy()
+	}
+	if _, err := launchctl("unload", "-w", path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Status ask launchd whether the listener is running
+func (s *Service) Status() (Status, error) {
+	path, err := s.plistPath()
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Path: path}
+	if _, err := os.Stat(path); err != nil {
+		status.Detail = "not installed"
+		return status, nil
+	}
+	status.Installed = true
+	out, err := launchctl("list", s.Label())
+	if err != nil {
+		status.Detail = "not loaded"
+		return status, nil
+	}
+	if m := pidPattern.FindStringSubmatch(out); m != nil {
+		status.Active = true
+		status.Detail = "running as pid " + m[1]
+		return status, nil
+	}
+	status.Detail = "loaded, not running"
+	return status, nil
+}
+
+// Logs return the command showing the output of the listener
+func (s *Service) Logs() string {
+	logs, _ := s.logPath()
+	return "tail -f " + logs
+}
+
+// path of the user agent
+func (s *Service) plistPath() (string, error) {
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", s.Label()+".plist"), nil
+}
+
+// path the agent writes its output to
+func (s *Service) logPath() (string, error) {
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "Logs", s.Name+".log"), nil
+}
+
+// home directory of the current user
+func homeDir() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("can not find the home directory: %v", err)
+	}
+	return usr.HomeDir, nil
+}
+
+// run launchctl and return its trimmed output
+func launchctl(args ...string) (string, error) {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	text := strings.TrimSpace(string(out))
+	if err != nil {
+		return text, fmt.Errorf("launchctl %s: %s", strings.Join(args, " "), text)
+	}
+	return text, nil
+}
@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Install write the systemd user unit, enable it and start the listener
+func (s *Service) Install() error {
+	path, err := s.unitPath()
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, s.SystemdUnit()); err != nil {
+		return err
+	}
+	if err := removeLegacy(); err != nil {
+		return err
+	}
+	if _, err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	_, err = systemctl("enable", "--now", s.unit())
+	return err
+}
+
+// Uninstall stop and disable the listener and remove the unit
+func (s *Service) Uninstall() error {
+	path, err := s.unitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return removeLegacy()
+	}
+	if _, err := systemctl("disable", "--now", s.unit()); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	_, err = systemctl("daemon-reload")
+	return err
+}
+
+// Status ask systemd whether the listener is running
+func (s *Service) Status() (Status, error) {
+	path, err := s.unitPath()
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Path: path}
+	if _, err := os.Stat(path); err != nil {
+		status.Detail = "not installed"
+		return status, nil
+	}
+	status.Installed = true
+	// is-active exits non zero for every state but active
+	out, err := systemctl("is-active", s.unit())
+	status.Detail = out
+	status.Active = err == nil && out == "active"
+	if _, ok := err.(*exec.Error); ok {
+		return status, err
+	}
+	return status, nil
+}
+
+// Logs return the command showing the output of the listener
+func (s *Service) Logs() string {
+	return "journalctl --user -u " + s.unit()
+}
+
+// name of the systemd unit
+func (s *Service) unit() string {
+	return s.Name + ".service"
+}
+
+// path of the user unit, under XDG_CONFIG_HOME when set
+func (s *Service) unitPath() (string, error) {
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("can not find the home directory: %v", err)
+		}
+		config = filepath.Join(usr.HomeDir, ".config")
+	}
+	return filepath.Join(config, "systemd", "user", s.unit()), nil
+}
+
+// run systemctl on the user instance and return its trimmed output
+func systemctl(args ...string) (string, error) {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	text := strings.TrimSpace(string(out))
+	if _, ok := err.(*exec.ExitError); ok && args[0] != "is-active" {
+		return text, fmt.Errorf("systemctl %s: %s", strings.Join(args, " "), text)
+	}
+	return text, err
+}
@@ -0,0 +1,47 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package service
+
+import (
+	"github.com/ProtonMail/go-autostart"
+)
+
+// Install register the listener to start at login, there is no service
+// manager to restart it
+func (s *Service) Install() error {
+	if err := removeLegacy(); err != nil {
+		return err
+	}
+	return s.app().Enable()
+}
+
+// Uninstall remove the autostart entry
+func (s *Service) Uninstall() error {
+	if err := removeLegacy(); err != nil {
+		return err
+	}
+	if !s.app().IsEnabled() {
+		return nil
+	}
+	return s.app().Disable()
+}
+
+// Status tell whether the listener is registered, whether it runs is unknown
+func (s *Service) Status() (Status, error) {
+	status := Status{Installed: s.app().IsEnabled(), Detail: "not installed"}
+	if status.Installed {
+		status.Detail = "starts at login"
+	}
+	return status, nil
+}
+
+// Logs return how to see the output of the listener
+func (s *Service) Logs() string {
+	return "the listener has no log file on this system"
+}
+
+// the autostart entry of the service
+func (s *Service) app() *autostart.App {
+	return &autostart.App{Name: s.Name, DisplayName: s.Description, Exec: s.Exec}
+}
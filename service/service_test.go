@@ -0,0 +1,64 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	defer os.Unsetenv("TASK_DB_FILE_PATH")
+	os.Setenv("TASK_DB_FILE_PATH", "tasks")
+	s, err := New("listen-reminder-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(s.Exec[0]) || s.Exec[1] != "listen-reminder-queue" {
+		t.Error("Service must run this executable by its absolute path", s.Exec)
+	}
+	if !filepath.IsAbs(s.Env["TASK_DB_FILE_PATH"]) || filepath.Base(s.Env["TASK_DB_FILE_PATH"]) != "tasks" {
+		t.Error("Service must keep the database path, made absolute", s.Env)
+	}
+}
+
+func TestService_SystemdUnit(t *testing.T) {
+	s := &Service{
+		Name:        "task-reminder",
+		Description: "Terminal Task reminder listener",
+		Exec:        []string{"/opt/my tools/task", "listen-reminder-queue"},
+		Env:         map[string]string{"TASK_DB_FILE_PATH": "/home/john/100%", "TASK_WEBHOOK_SECRET": `a"$b`},
+	}
+	unit := s.SystemdUnit()
+	for _, line := range []string{
+		`ExecStart="/opt/my tools/task" "listen-reminder-queue"`,
+		`Environment="TASK_DB_FILE_PATH=/home/john/100%%"`,
+		`Environment="TASK_WEBHOOK_SECRET=a\"$b"`,
+		"Restart=on-failure",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("Unit misses %q\n%s", line, unit)
+		}
+	}
+}
+
+func TestService_LaunchdPlist(t *testing.T) {
+	s := &Service{
+		Name: "task-reminder",
+		Exec: []string{"/Users/john/bin/task", "listen-reminder-queue"},
+		Env:  map[string]string{"TASK_DB_FILE_PATH": "/Users/john/R&D"},
+	}
+	plist := s.LaunchdPlist("/Users/john/Library/Logs/task-reminder.log")
+	for _, part := range []string{
+		"<string>com.thedevsaddam.task-reminder</string>",
+		"<string>/Users/john/bin/task</string>\n\t\t<string>listen-reminder-queue</string>",
+		"<key>TASK_DB_FILE_PATH</key>\n\t\t<string>/Users/john/R&amp;D</string>",
+		"<key>SuccessfulExit</key>\n\t\t<false/>",
+		"<key>StandardErrorPath</key>\n\t<string>/Users/john/Library/Logs/task-reminder.log</string>",
+	} {
+		if !strings.Contains(plist, part) {
+			t.Errorf("Plist misses %q\n%s", part, plist)
+		}
+	}
+}
@@ -1,22 +1,27 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	// zone database for --tz on systems that do not ship one
+	_ "time/tzdata"
 
-	"github.com/0xAX/notificator"
-	"github.com/ProtonMail/go-autostart"
 	"github.com/fatih/color"
 	"github.com/olebedev/when"
 	"github.com/olebedev/when/rules/common"
 	"github.com/olebedev/when/rules/en"
 	"github.com/olekukonko/tablewriter"
 	"github.com/segmentio/go-prompt"
+	"github.com/thedevsaddam/task/notifier"
+	"github.com/thedevsaddam/task/service"
 	"github.com/thedevsaddam/task/taskmanager"
 )
 
@@ -33,8 +38,58 @@ const usage = `Usage:
 		Show all pending tasks
 	$ task a Watch Games of thrones
 		Add a new task [Watch Games of thrones] to list
+	$ task a Submit the report --due "friday 5pm"
+		Add a new task that is due on friday
+	$ task due ID tomorrow 5pm
+		Set when task of ID is due, "none" clears it
+	$ task overdue
+		Show the pending tasks that are past their due date
+	$ task a Submit timesheet --every "friday 5pm"
+		Add a recurring task, completing it adds the next occurrence
+	$ task every ID weekdays 9:30am
+		Make task of ID recur, also takes a RRULE, "none" stops it
 	$ task remind Meeting with John tomorrow at 10:30pm
 		This will send you a desktop notification
+	$ task remind --tz America/New_York Call Jane tomorrow at 9am
+		Reminder time in another timezone, shown back in your local time
+	$ task remind --repeat 10m Take the pills at 9pm
+		Notify again every 10 minutes until the reminder is acknowledged
+	$ task snooze ID 10m
+		Reschedule the reminder of task ID, also takes a time like "tomorrow 9am"
+	$ task ack ID
+		Acknowledge the reminder of task ID, it stops repeating
+	$ task remind-add ID 1h before
+		Add another reminder to task ID, before its time or at a time like "friday 9am"
+	$ task remind-ls ID
+		Show all the reminders of task ID
+	$ task a Fix login +backend +urgent
+		Add a task with the tags backend and urgent
+	$ task a Renew the passport --pri high
+		Add a task with a priority, high, medium or low
+	$ task pri ID medium
+		Set the priority of task ID, "none" clears it
+	$ task -p work a Fix login
+		Add a task to the project work, -p also limits the lists to a project
+	$ task -p work.backend ls
+		Show the tasks of a project, nested projects like work.backend roll up into work
+	$ task projects
+		Show every project with its number of pending and total tasks
+	$ task move ID --to personal
+		Move task ID to another project, "none" takes it out of its project
+	$ task a --parent ID Write tests
+		Add a subtask to task ID, the list shows it indented below its parent
+	$ task c ID --force
+		Complete task ID even though it has pending subtasks
+	$ task dep ID --on OTHER
+		Block task ID until task OTHER is completed, --off removes the dependency
+	$ task next
+		Show the pending tasks that are not blocked, the most urgent first
+	$ task tag ID +frontend -backend
+		Add and remove tags of task ID
+	$ task ls +backend
+		Show the tasks carrying every given tag
+	$ task tags
+		Show every tag with its number of tasks
 	$ task del
 		Remove latest task from list
 	$ task rm ID
@@ -49,30 +104,69 @@ const usage = `Usage:
 		Mark task of ID as pending
 	$ task flush
 		Flush the database!
+	$ task migrate --to sqlite
+		Import the .task.json file into another storage (sqlite, bolt)
+	$ task db upgrade --dry-run
+		Upgrade the database to the current schema, --dry-run only reports the changes
+	$ task digest --email
+		Print today's digest, --email sends it through TASK_SMTP_ADDR instead
+	$ task digest --daily 7:30am
+		Email the digest every day at the given time, runs until stopped
+	$ task reminders missed
+		Show the reminders that were delivered late
 	$ task service-start
-		Run task as service if you are using reminder
+		Run task as service if you are using reminder, a systemd user unit or a launchd agent
+	$ task service-force-start
+		Reinstall the service for the current executable and TASK_* environment
+	$ task service-status
+		Report whether the reminder listener is running
 	$ task service-stop
 		Unregister Task from service!
+	$ task daemon ping
+		Ask the running reminder listener about its state
+	$ task daemon ls
+		Show the reminders the listener has scheduled
+	$ task daemon reload|pause|resume|stop
+		Reschedule from the database, hold notifications back, send them again or stop the listener
 `
 
 const (
 	completedSign  = "\u2713"
 	pendingSign    = "\u2613"
 	dateTimeLayout = "2006-01-02 15:04"
-	refreshRate    = 40
+	timeLayout     = "Mon, 01/02/06, 03:04PM"
+	zoneTimeLayout = "2006-01-02 15:04 MST"
+	// missedAfter is how late a reminder is delivered to count as missed
+	missedAfter = 2 * time.Minute
+	// flushEvery is how often the queued notifications are checked, they are
+	// only sent again once their backoff passed
+	flushEvery = 10 * time.Second
+)
+
+// exit codes, so scripts can tell the failures apart
+const (
+	exitError = iota + 1
+	exitNotFound
+	exitStorage
+	exitCorruptDB
 )
 
 var (
 	//task manager instance
-	tm = taskmanager.New()
-	//notifier
-	notify *notificator.Notificator
-	//run a service
-	service = autostart.App{
-		Name:        "thedevsaddam_terminal_task",
-		DisplayName: "Task",
-		Exec:        []string{"/usr/local/bin/task", "listen-reminder-queue"},
+	tm *taskmanager.List
+	//project of -p, empty for every task
+	project string
+	//commands that change the tasks by their least number of arguments, a
+	//running listener is told to reschedule after them
+	mutations = map[string]int{
+		"a": 2, "add": 2, "reminder": 2, "remind": 2, "remind-me": 2, "del": 1, "delete": 1, "r": 2, "rm": 2,
+		"e": 2, "m": 2, "u": 2, "c": 2, "d": 2, "done": 2, "i": 2, "p": 2, "pending": 2, "flush": 1,
+		"tag": 3, "pri": 3, "move": 3, "dep": 4, "snooze": 3, "ack": 2, "due": 3, "every": 3, "remind-add": 3,
 	}
+	//a +tag word, it starts with a letter so +1 stays in the description
+	tagPattern = regexp.MustCompile(`^\+[\pL_][\pL\pN_\-.:/]*$`)
+	//offset of remind-add, a number with a unit or a go duration
+	beforePattern = regexp.MustCompile(`^(?:(\d+)\s*(m|mins?|minutes?|h|hours?|d|days?|w|weeks?)|(\S+))\s+before$`)
 )
 
 func main() {
@@ -81,30 +175,171 @@ func main() {
 		fmt.Fprint(os.Stderr, usage)
 		flag.PrintDefaults()
 	}
+	flag.StringVar(&project, "p", "", "project to work in, like work or work.backend, the lists include the nested ones")
 	flag.Parse()
 	cmd, args, argsLen := flag.Arg(0), flag.Args(), len(flag.Args())
+	if p, err := taskmanager.ParseProject(project); err == nil {
+		project = p
+	} else {
+		fail(err)
+	}
+	if order := os.Getenv("TASK_SORT"); order != "" {
+		sortOrder, err := taskmanager.ParseSort(order)
+		if err != nil {
+			fail(err)
+		}
+		taskmanager.DefaultSort = sortOrder
+	}
+	if auto := os.Getenv("TASK_AUTO_COMPLETE_PARENT"); auto != "" {
+		enabled, err := strconv.ParseBool(auto)
+		if err != nil {
+			fail(fmt.Errorf("invalid TASK_AUTO_COMPLETE_PARENT %q, use true or false", auto))
+		}
+		taskmanager.AutoCompleteParents = enabled
+	}
+	tm = loadTasks()
+	//deferred, so the commands that return early signal too
+	if n, ok := mutations[cmd]; ok && argsLen >= n {
+		defer signalListener()
+	}
 
 	switch {
-	case cmd == "" || cmd == "l" || cmd == "ls" && argsLen == 1:
-		showTasksInTable(tm.GetAllTasks())
+	case (cmd == "" || cmd == "l" || cmd == "ls") && argsLen <= 1:
+		showTasksInTable(inProject(tm.GetAllTasks()))
 	case cmd == "a" || cmd == "add" && argsLen >= 1:
-		if len(args[1:]) <= 0 {
+		due, rest := extractFlag(args[1:], "--due")
+		every, rest := extractFlag(rest, "--every")
+		pri, rest := extractFlag(rest, "--pri")
+		parent, rest := extractFlag(rest, "--parent")
+		tags, rest := extractTags(rest)
+		if len(rest) <= 0 {
 			warningText(" Task description can not be empty \n")
 			return
 		}
-		tm.Add(strings.Join(args[1:], " "), "", "")
-		successText(" Added to list: " + strings.Join(args[1:], " ") + " ")
+		rule, err := parseEvery(every)
+		if err != nil {
+			fail(err)
+		}
+		parentId := 0
+		if parent != "" {
+			if parentId, err = strconv.Atoi(parent); err != nil || parentId <= 0 {
+				fail(fmt.Errorf("%w: parent %q", taskmanager.ErrInvalidId, parent))
+			}
+		}
+		priority, err := taskmanager.ParsePriority(pri)
+		if err != nil {
+			fail(err)
+		}
+		opts := taskmanager.AddOptions{Tags: tags, Recurrence: rule, Priority: priority, Project: project, ParentId: parentId}
+		if due != "" {
+			dueAt := parseWhen(due, time.Now())
+			opts.Due = &dueAt
+		}
+		if _, err := tm.AddTask(strings.Join(rest, " "), opts); err != nil {
+			fail(err)
+		}
+		successText(" Added to list: " + strings.Join(rest, " ") + " ")
 	case cmd == "reminder" || cmd == "remind" || cmd == "remind-me" && argsLen >= 1:
-		if len(args[1:]) <= 0 {
+		tz, rest := extractFlag(args[1:], "--tz")
+		repeat, rest := extractFlag(rest, "--repeat")
+		pri, rest := extractFlag(rest, "--pri")
+		tags, rest := extractTags(rest)
+		if len(rest) <= 0 {
 			warningText(" Task/Reminder description can not be empty \n")
 			return
 		}
-		reminder := strings.Join(args[1:], " ")
-		action, actionWhen := parseReminder(reminder)
-		tm.Add(action, "", actionWhen)
-		successText(" Reminder Added: " + action + " ")
+		loc, err := loadLocation(tz)
+		if err != nil {
+			fail(err)
+		}
+		repeatEvery, err := parseRepeat(repeat)
+		if err != nil {
+			fail(err)
+		}
+		priority, err := taskmanager.ParsePriority(pri)
+		if err != nil {
+			fail(err)
+		}
+		reminder := strings.Join(rest, " ")
+		action, actionWhen := parseReminder(reminder, time.Now().In(loc))
+		opts := taskmanager.AddOptions{Tags: tags, RemindAt: &actionWhen, RepeatEvery: repeatEvery, Priority: priority, Project: project}
+		if _, err := tm.AddTask(action, opts); err != nil {
+			fail(err)
+		}
+		successText(" Reminder Added: " + action + " at " + actionWhen.In(time.Local).Format(zoneTimeLayout) + " ")
+	case (cmd == "l" || cmd == "ls") && argsLen >= 2:
+		tags, rest := extractTags(args[1:])
+		if len(rest) > 0 {
+			errorText(" [Filter by tags like +backend, not " + strings.Join(rest, " ") + "] ")
+			return
+		}
+		showTasksInTable(inProject(tm.GetTasksByTags(tags...)))
+	case cmd == "pri" && argsLen == 3:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		priority, err := taskmanager.ParsePriority(flag.Arg(2))
+		if err != nil {
+			fail(err)
+		}
+		task, err := tm.UpdateTaskPriority(id, priority)
+		if err != nil {
+			fail(err)
+		}
+		if task.Priority == taskmanager.PriorityNone {
+			successText(" Priority removed: " + task.Description + " ")
+			return
+		}
+		successText(" Priority " + task.Priority.String() + ": " + task.Description + " ")
+	case cmd == "next" && argsLen == 1:
+		showNextTasks(inProject(tm.GetNextTasks(time.Now())))
+	case cmd == "dep" && argsLen == 4 && (flag.Arg(2) == "--on" || flag.Arg(2) == "--off"):
+		id, _ := strconv.Atoi(flag.Arg(1))
+		on, _ := strconv.Atoi(flag.Arg(3))
+		if flag.Arg(2) == "--off" {
+			task, err := tm.RemoveDependency(id, on)
+			if err != nil {
+				fail(err)
+			}
+			successText(" " + task.Description + " does not wait for task " + flag.Arg(3) + " anymore ")
+			return
+		}
+		task, err := tm.AddDependency(id, on)
+		if err != nil {
+			fail(err)
+		}
+		successText(" " + task.Description + " waits for task " + flag.Arg(3) + " ")
+	case cmd == "projects" && argsLen == 1:
+		showProjects(tm.GetProjects())
+	case cmd == "move" && argsLen >= 3:
+		to, rest := extractFlag(args[2:], "--to")
+		if len(rest) > 0 || to == "" {
+			errorText(" [Move a task with --to PROJECT, \"none\" takes it out of its project] ")
+			return
+		}
+		id, _ := strconv.Atoi(flag.Arg(1))
+		task, err := tm.MoveTask(id, to)
+		if err != nil {
+			fail(err)
+		}
+		if task.Project == "" {
+			successText(" Removed from its project: " + task.Description + " ")
+			return
+		}
+		successText(" Moved to " + task.Project + ": " + task.Description + " ")
+	case cmd == "tags" && argsLen == 1:
+		showTags(tm.GetTags())
+	case cmd == "tag" && argsLen >= 3:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		add, remove, err := parseTagChanges(args[2:])
+		if err != nil {
+			fail(err)
+		}
+		task, err := tm.TagTask(id, add, remove)
+		if err != nil {
+			fail(err)
+		}
+		successText(" Tags of " + task.Description + ": " + tagsText(task.Tags) + " ")
 	case cmd == "p" || cmd == "pending" && argsLen == 1:
-		showTasksInTable(tm.GetPendingTasks())
+		showTasksInTable(inProject(tm.GetPendingTasks()))
 	case cmd == "del" || cmd == "delete" && argsLen == 1:
 		p := prompt.Choose("Do you want to delete latest task?", []string{"yes", "no"})
 		if p == 1 {
@@ -113,12 +348,15 @@ func main() {
 		}
 		err := tm.RemoveTask(tm.GetLastId())
 		if err != nil {
-			errorText(err.Error())
-			return
+			fail(err)
 		}
 		successText(" Removed latest task ")
 	case cmd == "r" || cmd == "rm" && argsLen == 2:
 		id, _ := strconv.Atoi(flag.Arg(1))
+		if task, err := tm.GetTask(id); err == nil && len(tm.Subtasks(task)) > 0 {
+			removeTree(task)
+			return
+		}
 		p := prompt.Choose("Do you want to delete task of id "+flag.Arg(1)+" ?", []string{"yes", "no"})
 		if p == 1 {
 			warningText(" Task delete aboarted! ")
@@ -126,36 +364,115 @@ func main() {
 		}
 		err := tm.RemoveTask(id)
 		if err != nil {
-			errorText(err.Error())
-			return
+			fail(err)
 		}
 		successText(" Task " + strconv.Itoa(id) + " removed! ")
 	case cmd == "e" || cmd == "m" || cmd == "u" && argsLen >= 2:
 		id, _ := strconv.Atoi(flag.Arg(1))
-		ok, _ := tm.UpdateTask(id, strings.Join(args[2:], " "))
+		ok, err := tm.UpdateTask(id, strings.Join(args[2:], " "))
+		if err != nil {
+			fail(err)
+		}
 		successText(ok)
 	case cmd == "c" || cmd == "d" || cmd == "done" && argsLen >= 2:
 		id, _ := strconv.Atoi(flag.Arg(1))
-		task, err := tm.MarkAsCompleteTask(id)
+		force := argsLen == 3 && flag.Arg(2) == "--force"
+		parents := pendingParents(id)
+		complete := tm.MarkAsCompleteTask
+		if force {
+			complete = tm.ForceCompleteTask
+		}
+		task, err := complete(id)
+		if errors.Is(err, taskmanager.ErrOpenSubtasks) {
+			errorText(" " + err.Error() + ", complete them first or use --force ")
+			os.Exit(exitError)
+		}
 		if err != nil {
-			errorText(err.Error())
-			return
+			fail(err)
 		}
 		successText(" " + completedSign + " " + task.Description)
+		for _, parent := range parents {
+			if p, err := tm.GetTask(parent.Id); err == nil && p.Completed != nil {
+				printText(" " + completedSign + " " + p.Description + ", its last subtask is done")
+			}
+		}
+		if next, err := tm.GetTask(tm.GetLastId()); err == nil && task.Recurrence != "" && next.Recurrence == task.Recurrence && next.Id != task.Id {
+			printText(" Next: " + next.Description + " due " + next.Due.In(time.Local).Format(timeLayout))
+		}
 	case cmd == "i" || cmd == "p" || cmd == "pending" && argsLen >= 2:
 		id, _ := strconv.Atoi(flag.Arg(1))
 		task, err := tm.MarkAsPendingTask(id)
 		if err != nil {
-			errorText(err.Error())
-			return
+			fail(err)
 		}
 		successText(" " + pendingMark() + " " + task.Description)
+	case cmd == "snooze" && argsLen >= 3:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		task, err := tm.SnoozeTask(id, parseWhen(strings.Join(args[2:], " "), time.Now()))
+		if err != nil {
+			fail(err)
+		}
+		successText(" Reminder snoozed: " + task.Description + " at " + task.RemindAt.In(time.Local).Format(zoneTimeLayout) + " ")
+	case cmd == "ack" && argsLen == 2:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		task, err := tm.AckTask(id)
+		if err != nil {
+			fail(err)
+		}
+		successText(" Reminder acknowledged: " + task.Description + " ")
+	case cmd == "due" && argsLen >= 3:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		var due *time.Time
+		if when := strings.Join(args[2:], " "); when != "none" {
+			dueAt := parseWhen(when, time.Now())
+			due = &dueAt
+		}
+		task, err := tm.UpdateTaskDue(id, due)
+		if err != nil {
+			fail(err)
+		}
+		if task.Due == nil {
+			successText(" Due date removed: " + task.Description + " ")
+			return
+		}
+		successText(" Due: " + task.Description + " on " + task.Due.In(time.Local).Format(timeLayout) + " ")
+	case cmd == "every" && argsLen >= 3:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		rule, err := parseEvery(strings.Join(args[2:], " "))
+		if err != nil {
+			fail(err)
+		}
+		task, err := tm.UpdateTaskRecurrence(id, rule)
+		if err != nil {
+			fail(err)
+		}
+		if task.Recurrence == "" {
+			successText(" Stopped recurring: " + task.Description + " ")
+			return
+		}
+		successText(" Recurring: " + task.Description + " due " + task.Due.In(time.Local).Format(timeLayout) + " ")
+	case cmd == "overdue" && argsLen == 1:
+		showTasksInTable(inProject(tm.GetOverdueTasks(time.Now())))
+	case cmd == "remind-add" && argsLen >= 3:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		task, err := tm.AddReminder(id, parseReminderWhen(strings.Join(args[2:], " "), time.Now()))
+		if err != nil {
+			fail(err)
+		}
+		alarms := task.Alarms()
+		successText(" Reminder Added: " + task.Description + " at " + alarms[len(alarms)-1].At.In(time.Local).Format(zoneTimeLayout) + " ")
+	case cmd == "remind-ls" && argsLen == 2:
+		id, _ := strconv.Atoi(flag.Arg(1))
+		task, err := tm.GetTask(id)
+		if err != nil {
+			fail(err)
+		}
+		showReminders(task)
 	case cmd == "s" && argsLen == 2:
 		id, _ := strconv.Atoi(flag.Arg(1))
 		task, err := tm.GetTask(id)
 		if err != nil {
-			errorText(err.Error())
-			return
+			fail(err)
 		}
 		showTask(task)
 	case cmd == "flush":
@@ -166,49 +483,273 @@ func main() {
 		}
 		err := tm.FlushDB()
 		if err != nil {
-			errorText(err.Error())
-			return
+			fail(err)
 		}
 		successText(" Database flushed successfully! ")
+	case cmd == "migrate" && argsLen == 3 && flag.Arg(1) == "--to":
+		migrate(flag.Arg(2))
+	case cmd == "db" && flag.Arg(1) == "upgrade" && argsLen <= 3:
+		if argsLen == 3 && flag.Arg(2) != "--dry-run" {
+			fail(fmt.Errorf("unknown upgrade option %q, only --dry-run", flag.Arg(2)))
+		}
+		dbUpgrade(argsLen == 3)
 	case cmd == "service-start" && argsLen == 1:
 		serviceStart()
 	case cmd == "service-force-start" && argsLen == 1:
 		serviceForceStart()
 	case cmd == "service-stop" && argsLen == 1:
 		serviceStop()
+	case cmd == "service-status" && argsLen == 1:
+		serviceStatus()
+	case cmd == "digest" && argsLen <= 4:
+		daily, rest := extractFlag(args[1:], "--daily")
+		switch {
+		case daily != "" && len(rest) <= 1:
+			digestDaily(daily)
+		case len(rest) == 1 && rest[0] == "--email":
+			sendDigest()
+		case len(rest) == 0:
+			fmt.Print(notifier.DigestText(tm.Digest(time.Now())))
+		default:
+			errorText(" [Unknown digest option " + strings.Join(rest, " ") + "] ")
+		}
+	case cmd == "reminders" && flag.Arg(1) == "missed" && argsLen == 2:
+		showMissedReminders(tm.GetMissedReminders(missedAfter))
 	case cmd == "listen-reminder-queue" && argsLen == 1:
 		listenReminderQueue()
+	case cmd == "daemon" && argsLen == 2:
+		daemonCommand(flag.Arg(1))
 	case cmd == "h" || cmd == "v":
 		fmt.Fprint(os.Stderr, usage)
 	default:
 		errorText(" [No command found by " + cmd + "] ")
 		fmt.Fprint(os.Stderr, "\n"+usage)
 	}
+}
+
+//open the task list from the configured storage
+func openTasks() (*taskmanager.List, error) {
+	storage, err := taskmanager.DefaultStorage()
+	if err != nil {
+		return nil, err
+	}
+	return taskmanager.New(storage)
+}
+
+//load the task list, exit when the database is not usable
+func loadTasks() *taskmanager.List {
+	tasks, err := openTasks()
+	if err != nil {
+		fail(err)
+	}
+	return tasks
+}
 
+//print the error and exit with the code of its kind
+func fail(err error) {
+	errorText(" " + err.Error() + " ")
+	os.Exit(exitCode(err))
+}
+
+//map the taskmanager errors to exit codes
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, taskmanager.ErrNotFound), errors.Is(err, taskmanager.ErrInvalidId):
+		return exitNotFound
+	case errors.Is(err, taskmanager.ErrCorruptDB):
+		return exitCorruptDB
+	case errors.Is(err, taskmanager.ErrStorage):
+		return exitStorage
+	}
+	return exitError
 }
 
 //show tasks list in table
 func showTasksInTable(tasks taskmanager.Tasks) {
 	fmt.Fprintln(os.Stdout, "")
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"ID", "Description", completedSign + "/" + pendingMark(), "Created"})
+	table.SetHeader([]string{"ID", "Pri", "Description", completedSign + "/" + pendingMark(), "Due", "Created"})
 	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
-	table.SetFooter([]string{"", "Total: " + strconv.Itoa(tm.TotalTask()), "", "Pending: " + strconv.Itoa(tm.PendingTask())})
+	scope := inProject(tm.Tasks)
+	table.SetFooter([]string{"", "", "Total: " + strconv.Itoa(scope.TotalTask()) + projectCounts(tm.GetProjects()), "", "", "Pending: " + strconv.Itoa(scope.PendingTask())})
 	table.SetCenterSeparator("|")
 	table.SetRowLine(true)
-	for _, task := range tasks {
+	for _, node := range tasks.Tree() {
+		task := node.Task
 		//set completed icon
 		status := pendingSign
-		if task.Completed != "" {
+		if task.Completed != nil {
 			status = completedSign
 		} else {
 			status = pendingMark()
 		}
+		description := treeIndent(node.Depth) + task.Description
+		if len(task.Tags) > 0 {
+			description += " " + tagsColor(tagsText(task.Tags))
+		}
+		if task.Project != "" && task.Project != project {
+			description += " " + projectColor("@"+task.Project)
+		}
 		table.Append([]string{
 			strconv.Itoa(task.Id),
-			task.Description,
+			priorityText(task),
+			description,
 			status,
-			task.Created,
+			dueText(task, time.Now()),
+			task.Created.In(time.Local).Format(timeLayout),
+		})
+	}
+	table.Render()
+	fmt.Fprintln(os.Stdout, "")
+}
+
+//format the due date of a task, overdue in red and due today in yellow
+func dueText(task taskmanager.Task, now time.Time) string {
+	if task.Due == nil {
+		return ""
+	}
+	due := task.Due.In(time.Local)
+	text := due.Format(timeLayout)
+	if task.Completed != nil || runtime.GOOS == "windows" {
+		return text
+	}
+	now = now.In(time.Local)
+	switch {
+	case due.Before(now):
+		return color.New(color.Bold, color.FgRed).Sprint(text)
+	case due.Year() == now.Year() && due.YearDay() == now.YearDay():
+		return color.New(color.Bold, color.FgYellow).Sprint(text)
+	}
+	return text
+}
+
+//show the reminders that were delivered late
+func showMissedReminders(alarms []taskmanager.Alarm) {
+	fmt.Fprintln(os.Stdout, "")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Description", "Remind at", "Notified at"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetFooter([]string{"", "", "", "Missed: " + strconv.Itoa(len(alarms))})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+	for _, alarm := range alarms {
+		table.Append([]string{
+			strconv.Itoa(alarm.Task.Id),
+			alarm.Task.Description,
+			alarm.At.In(time.Local).Format(zoneTimeLayout),
+			alarm.NotifiedAt.In(time.Local).Format(zoneTimeLayout),
+		})
+	}
+	table.Render()
+	fmt.Fprintln(os.Stdout, "")
+}
+
+//show the tasks that can be worked on, the most urgent first
+func showNextTasks(tasks taskmanager.Tasks) {
+	now := time.Now()
+	fmt.Fprintln(os.Stdout, "")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Pri", "Description", "Due", "Urgency"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetFooter([]string{"", "", "Next: " + strconv.Itoa(len(tasks)), "", ""})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+	for _, task := range tasks {
+		description := task.Description
+		if len(task.Tags) > 0 {
+			description += " " + tagsColor(tagsText(task.Tags))
+		}
+		if task.Project != "" && task.Project != project {
+			description += " " + projectColor("@"+task.Project)
+		}
+		table.Append([]string{
+			strconv.Itoa(task.Id),
+			priorityText(task),
+			description,
+			dueText(task, now),
+			strconv.FormatFloat(tm.Urgency(task, now), 'f', 1, 64),
+		})
+	}
+	table.Render()
+	fmt.Fprintln(os.Stdout, "")
+}
+
+//show every project with its number of tasks, nested ones indented
+func showProjects(projects []taskmanager.ProjectCount) {
+	fmt.Fprintln(os.Stdout, "")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Project", "Pending", "Total"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetFooter([]string{"", "", "Projects: " + strconv.Itoa(len(projects))})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+	for _, p := range projects {
+		depth := strings.Count(p.Project, ".")
+		table.Append([]string{strings.Repeat("  ", depth) + p.Project, strconv.Itoa(p.Pending), strconv.Itoa(p.Total)})
+	}
+	table.Render()
+	fmt.Fprintln(os.Stdout, "")
+}
+
+//show every tag with its number of tasks
+func showTags(tags []taskmanager.TagCount) {
+	fmt.Fprintln(os.Stdout, "")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Tag", "Pending", "Total"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetFooter([]string{"", "", "Tags: " + strconv.Itoa(len(tags))})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+	for _, tag := range tags {
+		table.Append([]string{"+" + tag.Tag, strconv.Itoa(tag.Pending), strconv.Itoa(tag.Total)})
+	}
+	table.Render()
+	fmt.Fprintln(os.Stdout, "")
+}
+
+//show the reminders scheduled by the listener, the next one first
+func showAlarms(alarms []taskmanager.Alarm) {
+	fmt.Fprintln(os.Stdout, "")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Description", "#", "Remind at"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetFooter([]string{"", "", "", "Scheduled: " + strconv.Itoa(len(alarms))})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+	for _, alarm := range alarms {
+		table.Append([]string{
+			strconv.Itoa(alarm.Task.Id),
+			alarm.Task.Description,
+			strconv.Itoa(alarm.Index),
+			alarm.At.In(time.Local).Format(zoneTimeLayout),
+		})
+	}
+	table.Render()
+	fmt.Fprintln(os.Stdout, "")
+}
+
+//show every reminder of a task
+func showReminders(task taskmanager.Task) {
+	fmt.Fprintln(os.Stdout, "")
+	printBoldText(task.Description)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Remind at", "When", "Notified at"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetRowLine(true)
+	for _, alarm := range task.Alarms() {
+		when := "at time"
+		if alarm.Index > 0 {
+			when = "absolute"
+			if r := task.Reminders[alarm.Index-1]; r.At == nil {
+				when = formatMinutes(r.Before) + " before"
+			}
+		}
+		table.Append([]string{
+			strconv.Itoa(alarm.Index),
+			alarm.At.In(time.Local).Format(zoneTimeLayout),
+			when,
+			formatTime(alarm.NotifiedAt, zoneTimeLayout),
 		})
 	}
 	table.Render()
@@ -223,12 +764,311 @@ func showTask(task taskmanager.Task) {
 	printText("ID: " + strconv.Itoa(task.Id))
 	printText("UID: " + task.UID)
 	printText("Description: " + task.Description)
-	printText("Tag: " + task.Tag)
-	printText("Created: " + task.Created)
-	printText("Updated: " + task.Updated)
+	printText("Tags: " + tagsText(task.Tags))
+	if task.RemindAt != nil {
+		printText("Remind at: " + task.RemindAt.In(time.Local).Format(zoneTimeLayout))
+	}
+	if task.Due != nil {
+		printText("Due: " + task.Due.In(time.Local).Format(timeLayout))
+	}
+	if task.Recurrence != "" {
+		printText("Every: " + task.Recurrence)
+	}
+	if task.Priority != taskmanager.PriorityNone {
+		printText("Priority: " + task.Priority.String())
+	}
+	if task.Project != "" {
+		printText("Project: " + task.Project)
+	}
+	if parent, ok := tm.ParentOf(task); ok {
+		printText("Parent: " + strconv.Itoa(parent.Id) + " " + parent.Description)
+	}
+	if subtasks := tm.Subtasks(task); len(subtasks) > 0 {
+		printText("Subtasks:")
+		showSubtasks(task, 1)
+	}
+	if blockers := tm.BlockedBy(task); len(blockers) > 0 {
+		printText("Blocked by: " + tasksText(blockers))
+	}
+	if blocked := tm.Blocking(task); len(blocked) > 0 {
+		printText("Blocks: " + tasksText(blocked))
+	}
+	if task.RepeatEvery > 0 {
+		printText("Repeat: every " + strconv.Itoa(task.RepeatEvery) + "m until acknowledged")
+	}
+	printText("Created: " + task.Created.In(time.Local).Format(timeLayout))
+	printText("Updated: " + formatTime(task.Updated, timeLayout))
 	fmt.Fprintln(os.Stdout, "")
 }
 
+//list tasks by their id and description
+func tasksText(tasks taskmanager.Tasks) string {
+	sort.Sort(tasks)
+	var list []string
+	for i := len(tasks) - 1; i >= 0; i-- {
+		list = append(list, strconv.Itoa(tasks[i].Id)+" "+tasks[i].Description)
+	}
+	return strings.Join(list, ", ")
+}
+
+//print the subtasks of a task as a tree below it
+func showSubtasks(task taskmanager.Task, depth int) {
+	subtasks := tm.Subtasks(task)
+	sort.Sort(subtasks)
+	for i := len(subtasks) - 1; i >= 0; i-- {
+		status := pendingMark()
+		if subtasks[i].Completed != nil {
+			status = completedSign
+		}
+		printText(treeIndent(depth) + status + " " + strconv.Itoa(subtasks[i].Id) + " " + subtasks[i].Description)
+		showSubtasks(subtasks[i], depth+1)
+	}
+}
+
+//indent a subtask by its depth in the task tree
+func treeIndent(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	return strings.Repeat("  ", depth-1) + "\u2514 "
+}
+
+//the pending parents of a task, to tell which ones completing it closes
+func pendingParents(id int) taskmanager.Tasks {
+	var parents taskmanager.Tasks
+	task, err := tm.GetTask(id)
+	for err == nil {
+		var ok bool
+		if task, ok = tm.ParentOf(task); !ok || task.Completed != nil || len(parents) > len(tm.Tasks) {
+			break
+		}
+		parents = append(parents, task)
+	}
+	return parents
+}
+
+//remove a task that has subtasks, asking whether they go along
+func removeTree(task taskmanager.Task) {
+	p := prompt.Choose("Task "+strconv.Itoa(task.Id)+" has subtasks, do you want to delete them as well?", []string{"yes, with its subtasks", "only the task, its subtasks move up", "no"})
+	switch p {
+	case 0:
+		n, err := tm.RemoveTaskTree(task.Id)
+		if err != nil {
+			fail(err)
+		}
+		successText(" Task " + strconv.Itoa(task.Id) + " removed with " + strconv.Itoa(n-1) + " subtasks! ")
+	case 1:
+		if err := tm.RemoveTask(task.Id); err != nil {
+			fail(err)
+		}
+		successText(" Task " + strconv.Itoa(task.Id) + " removed! ")
+	default:
+		warningText(" Task delete aboarted! ")
+	}
+}
+
+//pull a "--name value" or "--name=value" flag out of the command arguments
+func extractFlag(args []string, name string) (string, []string) {
+	value, rest := "", []string{}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == name && i+1 < len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], name+"="):
+			value = strings.TrimPrefix(args[i], name+"=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return value, rest
+}
+
+//split the +tag words off the arguments
+func extractTags(args []string) ([]string, []string) {
+	var tags, rest []string
+	for _, arg := range args {
+		if tagPattern.MatchString(arg) {
+			tags = append(tags, arg[1:])
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return tags, rest
+}
+
+//parse the +add and -remove arguments of the tag command
+func parseTagChanges(args []string) ([]string, []string, error) {
+	var add, remove []string
+	for _, arg := range args {
+		switch {
+		case tagPattern.MatchString(arg):
+			add = append(add, arg[1:])
+		case strings.HasPrefix(arg, "-") && tagPattern.MatchString("+"+arg[1:]):
+			remove = append(remove, arg[1:])
+		default:
+			return nil, nil, fmt.Errorf("invalid tag change %q, use +tag to add and -tag to remove", arg)
+		}
+	}
+	return add, remove, nil
+}
+
+//tags as +tag words
+func tagsText(tags []string) string {
+	words := make([]string, len(tags))
+	for i, tag := range tags {
+		words[i] = "+" + tag
+	}
+	return strings.Join(words, " ")
+}
+
+//priority of the task list as H, M or L, red, yellow and blue while pending
+func priorityText(task taskmanager.Task) string {
+	if task.Priority == taskmanager.PriorityNone {
+		return ""
+	}
+	text := strings.ToUpper(task.Priority.String()[:1])
+	if task.Completed != nil || runtime.GOOS == "windows" {
+		return text
+	}
+	switch task.Priority {
+	case taskmanager.PriorityHigh:
+		return color.New(color.Bold, color.FgRed).Sprint(text)
+	case taskmanager.PriorityMedium:
+		return color.New(color.Bold, color.FgYellow).Sprint(text)
+	}
+	return color.New(color.FgBlue).Sprint(text)
+}
+
+//keep the tasks of the -p project and its nested projects
+func inProject(tasks taskmanager.Tasks) taskmanager.Tasks {
+	if project == "" {
+		return tasks
+	}
+	return tasks.FilterProject(project)
+}
+
+//pending counts of the projects right below the -p project, or of the top
+//level ones, their nested projects rolled up
+func projectCounts(projects []taskmanager.ProjectCount) string {
+	var counts []string
+	for _, p := range projects {
+		name := p.Project
+		if project != "" {
+			if !strings.HasPrefix(name, project+".") {
+				continue
+			}
+			name = strings.TrimPrefix(name, project+".")
+		}
+		if strings.Contains(name, ".") || p.Pending == 0 {
+			continue
+		}
+		counts = append(counts, name+" "+strconv.Itoa(p.Pending))
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+	return " (pending " + strings.Join(counts, ", ") + ")"
+}
+
+//color the project of the task list
+func projectColor(text string) string {
+	if runtime.GOOS == "windows" {
+		return text
+	}
+	return color.New(color.FgMagenta).Sprint(text)
+}
+
+//color the tags of the task list
+func tagsColor(text string) string {
+	if runtime.GOOS == "windows" {
+		return text
+	}
+	return color.New(color.FgCyan).Sprint(text)
+}
+
+//parse the --repeat interval in whole minutes, empty means no repeat
+func parseRepeat(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("invalid repeat interval %q, use at least 1m", value)
+	}
+	return int(d / time.Minute), nil
+}
+
+//parse a snooze time, either a duration like 10m or a time like "tomorrow 9am"
+func parseWhen(value string, base time.Time) time.Time {
+	if d, err := time.ParseDuration(value); err == nil {
+		return base.Add(d)
+	}
+	_, t := parseReminder(value, base)
+	return t
+}
+
+//parse a remind-add time, "1h before" or "2 days before" the task's time,
+//anything else is read like a snooze time
+func parseReminderWhen(value string, base time.Time) taskmanager.Reminder {
+	if minutes, ok := parseBefore(value); ok {
+		return taskmanager.Reminder{Before: minutes}
+	}
+	at := parseWhen(value, base)
+	return taskmanager.Reminder{At: &at}
+}
+
+//parse an offset like "90m before", "1h30m before" or "2 days before" in minutes
+func parseBefore(value string) (int, bool) {
+	m := beforePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, false
+	}
+	if m[3] != "" {
+		d, err := time.ParseDuration(m[3])
+		return int(d / time.Minute), err == nil && d >= time.Minute
+	}
+	n, _ := strconv.Atoi(m[1])
+	unit := map[byte]int{'m': 1, 'h': 60, 'd': 24 * 60, 'w': 7 * 24 * 60}[m[2][0]]
+	return n * unit, n > 0
+}
+
+//format minutes as a short offset like 1d, 2h or 1h30m
+func formatMinutes(minutes int) string {
+	if minutes > 0 && minutes%(24*60) == 0 {
+		return strconv.Itoa(minutes/(24*60)) + "d"
+	}
+	return strings.TrimSuffix((time.Duration(minutes) * time.Minute).String(), "0s")
+}
+
+//parse the --every schedule, empty or "none" means the task does not recur
+func parseEvery(value string) (string, error) {
+	if value == "" || value == "none" {
+		return "", nil
+	}
+	return taskmanager.ParseEvery(value)
+}
+
+//load a timezone by its IANA name, empty means the local timezone
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+//format an optional time in local time, empty when it is not set
+func formatTime(t *time.Time, layout string) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(time.Local).Format(layout)
+}
+
 func printText(str string) {
 	fmt.Fprintf(os.Stdout, str+"\n")
 }
@@ -277,8 +1117,8 @@ func pendingMark() string {
 	return pending
 }
 
-//parse reminder
-func parseReminder(reminder string) (string, string) {
+//parse reminder, the date time reference is relative to base and in its timezone
+func parseReminder(reminder string, base time.Time) (string, time.Time) {
 	defer func() {
 		if r := recover(); r != nil {
 			errorText(" Your reminder does not contain any date time reference! ")
@@ -288,63 +1128,310 @@ func parseReminder(reminder string) (string, string) {
 	w := when.New(nil)
 	w.Add(en.All...)
 	w.Add(common.All...)
-	r, _ := w.Parse(reminder, time.Now())
+	r, _ := w.Parse(reminder, base)
 	action := strings.Replace(reminder, reminder[r.Index:r.Index+len(r.Text)], "", -1)
-	actionTime := r.Time.Format(dateTimeLayout)
+	actionTime := r.Time.Truncate(time.Minute)
 	return action, actionTime
 }
 
 //listen for reminder queue
 func listenReminderQueue() {
+	storage, err := taskmanager.DefaultStorage()
+	if err != nil {
+		fail(err)
+	}
+	notifiers, err := notifier.FromEnv()
+	if err != nil {
+		fail(err)
+	}
+	notifiers.Error = func(err error) {
+		errorText(" " + err.Error() + " ")
+	}
+	//failed notifications, also the ones from before a restart, are sent
+	//again off the scheduler
+	go func() {
+		for {
+			notifiers.Flush()
+			time.Sleep(flushEvery)
+		}
+	}()
+	scheduler := taskmanager.NewScheduler(storage, func(r taskmanager.Alarm) error {
+		title := "Task Reminder!"
+		if time.Since(r.At) > missedAfter {
+			title = "Missed Task Reminder!"
+		}
+		body := r.Task.Description
+		switch {
+		case r.Index > 0 && r.Task.Due != nil:
+			body += " due " + r.Task.Due.In(time.Local).Format(zoneTimeLayout)
+		case r.Index > 0 && r.Task.RemindAt != nil:
+			body += " at " + r.Task.RemindAt.In(time.Local).Format(zoneTimeLayout)
+		}
+		//the task stays pending, the scheduler records the delivery and backs
+		//off before retrying a failed one
+		return notifiers.Notify(notifier.Message{Title: title, Body: body, Task: r.Task})
+	})
+	scheduler.Error = func(err error) {
+		errorText(" " + err.Error() + " ")
+	}
+	done := make(chan struct{})
+	socket, err := service.SocketPath()
+	if err != nil {
+		fail(err)
+	}
+	control, err := service.Serve(socket, scheduler, func() { close(done) })
+	switch {
+	case errors.Is(err, service.ErrRunning):
+		fail(err)
+	case err != nil:
+		warningText(" Control socket is not available: " + err.Error() + " ")
+	default:
+		defer control.Close()
+	}
+	if err := scheduler.Run(done); err != nil {
+		fail(err)
+	}
+}
+
+//talk to the running reminder listener over its control socket
+func daemonCommand(action string) {
+	client, err := dialListener()
+	if err != nil {
+		fail(fmt.Errorf("reminder listener is not running: %v", err))
+	}
+	defer client.Close()
+	switch action {
+	case "ping":
+		reply, err := client.Ping()
+		if err != nil {
+			fail(err)
+		}
+		state := "running"
+		if reply.Paused {
+			state = "paused"
+		}
+		successText(" Listener " + state + ", pid " + strconv.Itoa(reply.PID) + ", up since " + reply.Started.In(time.Local).Format(zoneTimeLayout) + ", " + strconv.Itoa(reply.Scheduled) + " reminders scheduled ")
+	case "ls":
+		alarms, err := client.List()
+		if err != nil {
+			fail(err)
+		}
+		showAlarms(alarms)
+	case "reload":
+		err = client.Reload()
+	case "pause":
+		err = client.Pause()
+	case "resume":
+		err = client.Resume()
+	case "stop":
+		err = client.Shutdown()
+	default:
+		errorText(" [Unknown daemon command " + action + "] ")
+		return
+	}
+	if err != nil {
+		fail(err)
+	}
+	if action != "ping" && action != "ls" {
+		successText(" Listener: " + action + " done ")
+	}
+}
+
+//connect to the control socket of the reminder listener
+func dialListener() (*service.Client, error) {
+	socket, err := service.SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	return service.Dial(socket)
+}
+
+//make a running listener reschedule after the tasks changed, it also notices
+//the change of the database file but storages that poll would lag behind
+func signalListener() {
+	client, err := dialListener()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	client.Reload()
+}
+
+//import the json database into another storage
+func migrate(driver string) {
+	from, err := taskmanager.NewStorage("json")
+	if err != nil {
+		fail(err)
+	}
+	to, err := taskmanager.NewStorage(driver)
+	if err != nil {
+		fail(err)
+	}
+	n, err := taskmanager.Migrate(from, to)
+	if err != nil {
+		fail(err)
+	}
+	successText(" Migrated " + strconv.Itoa(n) + " tasks, set TASK_DB_DRIVER=" + driver + " to use it ")
+}
+
+//upgrade the database schema
+func dbUpgrade(dryRun bool) {
+	storage, err := taskmanager.DefaultStorage()
+	if err != nil {
+		fail(err)
+	}
+	upgrader, ok := storage.(taskmanager.Upgrader)
+	if !ok {
+		warningText(" Storage does not have a versioned schema ")
+		return
+	}
+	applied, err := upgrader.Upgrade(dryRun)
+	if err != nil {
+		fail(err)
+	}
+	if len(applied) == 0 {
+		successText(" Database schema is up to date ")
+		return
+	}
+	for _, m := range applied {
+		printText(m)
+	}
+	if dryRun {
+		warningText(" Dry run, nothing has been changed ")
+		return
+	}
+	successText(" Database upgraded ")
+}
+
+//email today's digest once
+func sendDigest() {
+	email, err := notifier.EmailFromEnv()
+	if err != nil {
+		fail(err)
+	}
+	if err := email.SendDigest(tm.Digest(time.Now())); err != nil {
+		fail(err)
+	}
+	successText(" Digest sent to " + strings.Join(email.To, ", ") + " ")
+}
+
+//email the digest every day at a time like 7:30am, a failed one is reported
+//and the next day is tried again
+func digestDaily(at string) {
+	email, err := notifier.EmailFromEnv()
+	if err != nil {
+		fail(err)
+	}
+	rule, err := taskmanager.ParseEvery("daily " + at)
+	if err == nil && !strings.Contains(rule, "BYHOUR=") {
+		err = fmt.Errorf("digest time %q has no time of day, use one like 7:30am", at)
+	}
+	if err != nil {
+		fail(err)
+	}
+	start := time.Now()
 	for {
-		rm := taskmanager.New()
-		reminderList := rm.GetReminderTasks()
-		now := time.Now().Format(dateTimeLayout)
-		for _, r := range reminderList {
-			if r.RemindAt == now {
-				desktopNotifier("Task Reminder!", r.Description)
-				rm.MarkAsCompleteTask(r.Id)
+		next, _, err := taskmanager.NextOccurrence(rule, start, time.Now())
+		if err != nil {
+			fail(err)
+		}
+		printText(" Next digest at " + next.In(time.Local).Format(zoneTimeLayout))
+		//short sleeps, timers do not advance while the machine is suspended
+		for wait := time.Until(next); wait > 0; wait = time.Until(next) {
+			if wait > time.Minute {
+				wait = time.Minute
 			}
+			time.Sleep(wait)
+		}
+		tasks, err := openTasks()
+		if err == nil {
+			err = email.SendDigest(tasks.Digest(time.Now()))
 		}
-		time.Sleep(time.Second * refreshRate)
+		if err != nil {
+			errorText(" " + err.Error() + " ")
+			continue
+		}
+		successText(" Digest sent to " + strings.Join(email.To, ", ") + " ")
 	}
 }
 
-//send desktop notification
-func desktopNotifier(title, body string) {
-	notify = notificator.New(notificator.Options{
-		DefaultIcon: "default.png",
-		AppName:     "Terminal Task",
-	})
-	notify.Push(title, body, "default.png", notificator.UR_NORMAL)
+//the reminder listener as a user service of this executable
+func listenerService() *service.Service {
+	s, err := service.New("listen-reminder-queue")
+	if err != nil {
+		fail(err)
+	}
+	return s
 }
 
-//enable auto start
+//install and start the listener service
 func serviceStart() {
-	if service.IsEnabled() {
+	s := listenerService()
+	if status, err := s.Status(); err == nil && status.Installed {
 		warningText("Task is already enabled as service!")
-	} else {
-		if err := service.Enable(); err != nil {
-			errorText(err.Error())
-		}
-		successText("Task has been registered as service!")
+		return
 	}
+	if err := s.Install(); err != nil {
+		fail(err)
+	}
+	successText("Task has been registered as service!")
 }
 
-//disable auto start
+//stop and remove the listener service
 func serviceStop() {
-	if service.IsEnabled() {
-		if err := service.Disable(); err != nil {
-			errorText(err.Error())
-		}
-		successText("Task has been removed from service!")
-	} else {
+	s := listenerService()
+	if status, err := s.Status(); err == nil && !status.Installed {
 		warningText("Task was not registered as service!")
+		return
 	}
+	if err := s.Uninstall(); err != nil {
+		fail(err)
+	}
+	successText("Task has been removed from service!")
 }
 
-//force start
+//reinstall the listener service, it picks up the current executable and environment
 func serviceForceStart() {
-	serviceStop()
-	serviceStart()
+	s := listenerService()
+	if err := s.Uninstall(); err != nil {
+		fail(err)
+	}
+	if err := s.Install(); err != nil {
+		fail(err)
+	}
+	successText("Task has been registered as service!")
+}
+
+//report whether the listener service is installed and running
+func serviceStatus() {
+	s := listenerService()
+	status, err := s.Status()
+	if err != nil {
+		fail(err)
+	}
+	//a listener answering on the control socket is alive, however it was started
+	if client, err := dialListener(); err == nil {
+		if reply, err := client.Ping(); err == nil {
+			status.Active = true
+			status.Detail = "pid " + strconv.Itoa(reply.PID) + ", " + strconv.Itoa(reply.Scheduled) + " reminders scheduled"
+			if reply.Paused {
+				status.Detail += ", paused"
+			}
+		}
+		client.Close()
+	}
+	if status.Path != "" {
+		printText(" Service: " + status.Path)
+	}
+	printText(" Logs: " + s.Logs())
+	switch {
+	case status.Active:
+		successText(" Listener is running: " + status.Detail + " ")
+	case status.Installed:
+		errorText(" Listener is not running: " + status.Detail + " ")
+		os.Exit(exitError)
+	default:
+		warningText(" Listener is not installed, run task service-start ")
+		os.Exit(exitError)
+	}
 }
@@ -1,18 +1,41 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/thedevsaddam/task/taskmanager"
 )
 
+func TestExitCode(t *testing.T) {
+	cases := map[error]int{
+		fmt.Errorf("%w: id 3", taskmanager.ErrNotFound):     exitNotFound,
+		fmt.Errorf("%w: disk full", taskmanager.ErrStorage): exitStorage,
+		taskmanager.ErrCorruptDB:                            exitCorruptDB,
+		errors.New("boom"):                                  exitError,
+	}
+	for err, code := range cases {
+		if exitCode(err) != code {
+			t.Errorf("Exit code of %v must be %d", err, code)
+		}
+	}
+}
+
 func Example_showTask() {
+	tm = &taskmanager.List{}
 	showTask(taskmanager.Task{
 		Id:          1,
 		UID:         "213e9bb0-79e8-4647-8902-8421271e1809",
 		Description: "Watch Pirates of the Caribbean: Dead Men Tell No Tales",
-		Tag:         "low",
-		Created:     "Fri, 07/21/17, 12:13PM",
-		Updated:     "Fri, 07/21/17, 12:15PM",
-		Completed:   "Fri, 07/21/17, 24:10PM",
+		Tags:        []string{"low", "movie"},
+		Priority:    taskmanager.PriorityHigh,
+		//loaded from a database written in another timezone
+		Created:   time.Date(2017, 7, 21, 12, 13, 0, 0, time.Local).In(time.FixedZone("UTC+13", 13*60*60)),
+		Updated:   timePtr(time.Date(2017, 7, 21, 12, 15, 0, 0, time.Local).In(time.FixedZone("UTC-11", -11*60*60))),
+		Completed: timePtr(time.Date(2017, 7, 22, 0, 10, 0, 0, time.Local)),
 	})
 	//output:
 	//
@@ -21,8 +44,88 @@ func Example_showTask() {
 	//ID: 1
 	//UID: 213e9bb0-79e8-4647-8902-8421271e1809
 	//Description: Watch Pirates of the Caribbean: Dead Men Tell No Tales
-	//Tag: low
+	//Tags: +low +movie
+	//Priority: high
 	//Created: Fri, 07/21/17, 12:13PM
 	//Updated: Fri, 07/21/17, 12:15PM
 	//
 }
+
+func TestExtractFlag(t *testing.T) {
+	value, rest := extractFlag([]string{"--tz", "Asia/Dhaka", "Call", "Jane"}, "--tz")
+	if value != "Asia/Dhaka" || len(rest) != 2 || rest[0] != "Call" {
+		t.Error("Failed to extract flag with separate value")
+	}
+	value, rest = extractFlag([]string{"Call", "--tz=UTC", "Jane"}, "--tz")
+	if value != "UTC" || len(rest) != 2 {
+		t.Error("Failed to extract flag with inline value")
+	}
+	if value, _ := extractFlag([]string{"Call", "Jane"}, "--tz"); value != "" {
+		t.Error("Missing flag must be empty")
+	}
+}
+
+func TestParseRepeat(t *testing.T) {
+	if minutes, err := parseRepeat("10m"); err != nil || minutes != 10 {
+		t.Error("Failed to parse repeat interval", minutes, err)
+	}
+	if minutes, err := parseRepeat(""); err != nil || minutes != 0 {
+		t.Error("Empty repeat interval must not repeat")
+	}
+	for _, value := range []string{"30s", "often"} {
+		if _, err := parseRepeat(value); err == nil {
+			t.Errorf("Repeat interval %q must be rejected", value)
+		}
+	}
+}
+
+func TestParseBefore(t *testing.T) {
+	for value, minutes := range map[string]int{
+		"1h before":     60,
+		"1h30m before":  90,
+		"1 day before":  24 * 60,
+		"2 days before": 2 * 24 * 60,
+		"15 min before": 15,
+	} {
+		if got, ok := parseBefore(value); !ok || got != minutes {
+			t.Errorf("parseBefore(%q) = %d, expected %d", value, got, minutes)
+		}
+	}
+	for _, value := range []string{"tomorrow 9am", "soon before", "0m before"} {
+		if _, ok := parseBefore(value); ok {
+			t.Errorf("%q must not be read as an offset", value)
+		}
+	}
+	if formatMinutes(24*60) != "1d" || formatMinutes(90) != "1h30m" || formatMinutes(45) != "45m" {
+		t.Error("Failed to format reminder offsets")
+	}
+}
+
+func TestDueText(t *testing.T) {
+	now := time.Date(2017, 7, 21, 12, 13, 0, 0, time.Local)
+	if dueText(taskmanager.Task{}, now) != "" {
+		t.Error("Task without due date must show nothing")
+	}
+	due := time.Date(2017, 7, 21, 17, 0, 0, 0, time.Local)
+	if text := dueText(taskmanager.Task{Due: &due}, now); !strings.Contains(text, "07/21/17, 05:00PM") {
+		t.Error("Failed to show the due date", text)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestExtractTags(t *testing.T) {
+	tags, rest := extractTags([]string{"Fix", "login", "+backend", "+1", "+Urgent", "a+b"})
+	if strings.Join(tags, ",") != "backend,Urgent" || strings.Join(rest, " ") != "Fix login +1 a+b" {
+		t.Error("Unexpected tags", tags, rest)
+	}
+	add, remove, err := parseTagChanges([]string{"+frontend", "-backend"})
+	if err != nil || strings.Join(add, ",") != "frontend" || strings.Join(remove, ",") != "backend" {
+		t.Error("Unexpected tag changes", add, remove, err)
+	}
+	if _, _, err := parseTagChanges([]string{"backend"}); err == nil {
+		t.Error("Tag change without + or - must fail")
+	}
+}
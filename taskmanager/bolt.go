@@ -0,0 +1,243 @@
+package taskmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// boltFileName is the default bolt database file name
+	boltFileName = ".task.db"
+	// boltTimeout is how long to wait for another process holding the database
+	boltTimeout = 5 * time.Second
+)
+
+var (
+	pendingBucket   = []byte("pending")
+	completedBucket = []byte("completed")
+	reminderBucket  = []byte("reminders")
+	metaBucket      = []byte("meta")
+	// schemaVersionKey holds the schema version of the records in the meta bucket
+	schemaVersionKey = []byte("schema_version")
+)
+
+// BoltStorage keeps every task as its own record in an embedded bolt database.
+// Pending and completed tasks live in separate buckets keyed by UID, the
+// reminders bucket indexes the UIDs of pending tasks that have a reminder
+// with the time of their first one.
+type BoltStorage struct {
+	Path string
+}
+
+// NewBoltStorage return a bolt storage for the given path
+func NewBoltStorage(path string) *BoltStorage {
+	return &BoltStorage{Path: path}
+}
+
+// Load read all the tasks from the pending and completed buckets in a read
+// only transaction, records of an older schema version are upgraded first
+func (bs *BoltStorage) Load() (Tasks, error) {
+	if _, err := os.Stat(bs.Path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var tasks Tasks
+	current := true
+	err := bs.view(func(tx *bolt.Tx) (err error) {
+		if tx.Bucket(metaBucket) == nil {
+			// nothing was saved yet
+			return nil
+		}
+		if current = recordsVersion(tx) == schemaVersion; current {
+			tasks, err = readRecords(tx)
+		}
+		return err
+	})
+	if err != nil || current {
+		return tasks, err
+	}
+	err = bs.do(func(tx *bolt.Tx) (err error) {
+		if _, err = bs.upgrade(tx, false); err != nil {
+			return err
+		}
+		tasks, err = readRecords(tx)
+		return err
+	})
+	return tasks, err
+}
+
+// Save write the task list in a single transaction, only the records
+// that actually changed are touched
+func (bs *BoltStorage) Save(tasks Tasks) error {
+	return bs.do(func(tx *bolt.Tx) error {
+		return saveRecords(tx, tasks)
+	})
+}
+
+// Upgrade bring the records to the current schema version
+func (bs *BoltStorage) Upgrade(dryRun bool) ([]string, error) {
+	var applied []string
+	err := bs.do(func(tx *bolt.Tx) (err error) {
+		applied, err = bs.upgrade(tx, dryRun)
+		return err
+	})
+	return applied, err
+}
+
+// run the migrations the records need inside tx, databases without
+// a version are from before the schema was versioned
+func (bs *BoltStorage) upgrade(tx *bolt.Tx, dryRun bool) ([]string, error) {
+	version := recordsVersion(tx)
+	if version == schemaVersion {
+		return nil, nil
+	}
+	var raws []interface{}
+	err := forEachRecord(tx, func(k, v []byte) error {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(v, &raw); err != nil {
+			return fmt.Errorf("%w: record %s: %v", ErrCorruptDB, k, err)
+		}
+		raws = append(raws, raw)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{"schema_version": version, "tasks": raws}
+	applied, err := upgradeDocument(doc)
+	if err != nil || dryRun {
+		return applied, err
+	}
+	tasks, err := rawTasks(doc)
+	if err != nil {
+		return applied, err
+	}
+	return applied, saveRecords(tx, tasks)
+}
+
+// schema version of the records, databases without a version are from
+// before the schema was versioned
+func recordsVersion(tx *bolt.Tx) int {
+	version := 1
+	if v := tx.Bucket(metaBucket).Get(schemaVersionKey); v != nil {
+		version, _ = strconv.Atoi(string(v))
+	}
+	return version
+}
+
+// decode every task record, pending ones first
+func readRecords(tx *bolt.Tx) (Tasks, error) {
+	var tasks Tasks
+	err := forEachRecord(tx, func(k, v []byte) error {
+		var task Task
+		if err := json.Unmarshal(v, &task); err != nil {
+			return fmt.Errorf("%w: record %s: %v", ErrCorruptDB, k, err)
+		}
+		tasks = append(tasks, task)
+		return nil
+	})
+	return tasks, err
+}
+
+// run fn on every task record, pending ones first
+func forEachRecord(tx *bolt.Tx, fn func(k, v []byte) error) error {
+	for _, name := range [][]byte{pendingBucket, completedBucket} {
+		if err := tx.Bucket(name).ForEach(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write the task list as records of the current schema version
+func saveRecords(tx *bolt.Tx, tasks Tasks) error {
+	pending, completed, reminders := tx.Bucket(pendingBucket), tx.Bucket(completedBucket), tx.Bucket(reminderBucket)
+	keep := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		key := []byte(recordKey(task))
+		keep[string(key)] = true
+		value, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		target, other := pending, completed
+		if task.Completed != nil {
+			target, other = completed, pending
+		}
+		if err := other.Delete(key); err != nil {
+			return err
+		}
+		if !bytes.Equal(target.Get(key), value) {
+			if err := target.Put(key, value); err != nil {
+				return err
+			}
+		}
+		if alarms := task.Alarms(); len(alarms) > 0 && task.Completed == nil {
+			err = reminders.Put(key, []byte(alarms[0].At.Format(time.RFC3339)))
+		} else {
+			err = reminders.Delete(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, b := range []*bolt.Bucket{pending, completed, reminders} {
+		var stale [][]byte
+		b.ForEach(func(k, v []byte) error {
+			if !keep[string(k)] {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		})
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Bucket(metaBucket).Put(schemaVersionKey, []byte(strconv.Itoa(schemaVersion)))
+}
+
+// Lock take an advisory lock next to the database file
+func (bs *BoltStorage) Lock() (func(), error) {
+	return lockFile(lockFilePath(bs.Path))
+}
+
+// Watch notify whenever the database file is modified
+func (bs *BoltStorage) Watch(done <-chan struct{}) (<-chan struct{}, error) {
+	return watchFile(bs.Path, done)
+}
+
+// open the database read only, run fn in a read transaction and close it
+// again, other processes may read meanwhile
+func (bs *BoltStorage) view(fn func(tx *bolt.Tx) error) error {
+	db, err := bolt.Open(bs.Path, 0644, &bolt.Options{Timeout: boltTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// open the database, run fn in a read-write transaction and close it again,
+// the database is not kept open as bolt locks the file for other processes
+func (bs *BoltStorage) do(fn func(tx *bolt.Tx) error) error {
+	db, err := bolt.Open(bs.Path, 0644, &bolt.Options{Timeout: boltTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{pendingBucket, completedBucket, reminderBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,98 @@
+package taskmanager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltStorage_SaveLoad(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	bs := NewBoltStorage(filepath.Join(dir, "tasks.db"))
+	tasks := Tasks{
+		{Id: 1, UID: "a", Description: "Go to store"},
+		{Id: 2, UID: "b", Description: "Learn golang testing", Completed: timeAt("2017-07-21T12:13:00Z")},
+		{Id: 3, UID: "c", Description: "Meeting with John", RemindAt: timeAt("2017-07-22T10:30:00Z")},
+	}
+	if err := bs.Save(tasks); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := bs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.TotalTask() != 3 || loaded.CompletedTask() != 1 || len(loaded.GetReminderTasks()) != 1 {
+		t.Error("Failed to load saved tasks from bolt!")
+	}
+}
+
+func TestBoltStorage_buckets(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	bs := NewBoltStorage(filepath.Join(dir, "tasks.db"))
+	tasks := Tasks{
+		{Id: 1, UID: "a", Description: "Go to store", RemindAt: timeAt("2017-07-22T10:30:00Z")},
+		{Id: 2, UID: "b", Description: "Learn golang testing"},
+	}
+	bs.Save(tasks)
+	// complete the reminder and remove the second task
+	tasks[0].Completed = timeAt("2017-07-21T12:13:00Z")
+	if err := bs.Save(tasks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	bs.do(func(tx *bolt.Tx) error {
+		if tx.Bucket(pendingBucket).Stats().KeyN != 0 {
+			t.Error("Pending bucket must be empty!")
+		}
+		if tx.Bucket(completedBucket).Get([]byte("a")) == nil {
+			t.Error("Completed task must be moved to completed bucket!")
+		}
+		if tx.Bucket(reminderBucket).Get([]byte("a")) != nil {
+			t.Error("Completed task must be removed from reminders!")
+		}
+		return nil
+	})
+}
+
+func TestBoltStorage_Load(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	bs := NewBoltStorage(filepath.Join(dir, "tasks.db"))
+	if tasks, err := bs.Load(); err != nil || len(tasks) != 0 {
+		t.Fatal("Missing database must load no tasks", tasks, err)
+	}
+	if _, err := os.Stat(bs.Path); !os.IsNotExist(err) {
+		t.Error("Loading must not create the database!")
+	}
+	// records of the first schema version are upgraded when they are loaded
+	bs.do(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Put([]byte("a"), []byte(`{"id":1,"uid":"a","description":"Go to store","tag":"Low"}`))
+	})
+	tasks, err := bs.Load()
+	if err != nil || len(tasks) != 1 || !tasks[0].HasTag("low") {
+		t.Fatal("Failed to upgrade the records!", tasks, err)
+	}
+	bs.view(func(tx *bolt.Tx) error {
+		if recordsVersion(tx) != schemaVersion {
+			t.Error("Upgraded records must be stored with the current version!")
+		}
+		return nil
+	})
+}
+
+func TestBoltStorage_corrupt(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	bs := NewBoltStorage(filepath.Join(dir, "tasks.db"))
+	bs.Save(Tasks{{Id: 1, UID: "a", Description: "Go to store"}})
+	bs.do(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).Put([]byte("a"), []byte(`{"id": 1, "desc`))
+	})
+	if _, err := New(bs); !errors.Is(err, ErrCorruptDB) {
+		t.Error("Corrupt record must return ErrCorruptDB", err)
+	}
+}
@@ -0,0 +1,216 @@
+package taskmanager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// the weights of Urgency
+const (
+	urgencyPriority = 2.0
+	urgencyDue      = 12.0
+	urgencyBlocking = 8.0
+	urgencyAge      = 2.0
+	// dueWindow is how far ahead a due date starts to count, a task due
+	// later counts a fifth of urgencyDue
+	dueWindow = 14 * 24 * time.Hour
+	// maxAge is the age where a task counts the whole urgencyAge
+	maxAge = 365 * 24 * time.Hour
+)
+
+// AddDependency make a task wait for another by id, a dependency that lets
+// the other task wait for this one returns ErrCycle
+func (l *List) AddDependency(id, on int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	if err := l.isValidId(on); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	o, err := l.getIndexIdNo(on)
+	if err != nil {
+		return Task{}, err
+	}
+	if i == o || l.dependsOn(o, i) {
+		return Task{}, fmt.Errorf("%w: task %d already waits for task %d", ErrCycle, on, id)
+	}
+	l.ensureUID(i)
+	l.ensureUID(o)
+	for _, u := range l.Tasks[i].DependsOn {
+		if u == l.Tasks[o].UID {
+			return l.Tasks[i], nil
+		}
+	}
+	l.Tasks[i].DependsOn = append(l.Tasks[i].DependsOn, l.Tasks[o].UID)
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+// RemoveDependency let a task stop waiting for another by id, removing a
+// dependency the task does not have is not an error
+func (l *List) RemoveDependency(id, on int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	o, err := l.getIndexIdNo(on)
+	if err != nil {
+		return Task{}, err
+	}
+	var dependsOn []string
+	for _, u := range l.Tasks[i].DependsOn {
+		if u != l.Tasks[o].UID {
+			dependsOn = append(dependsOn, u)
+		}
+	}
+	l.Tasks[i].DependsOn = dependsOn
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+// BlockedBy fetch the pending tasks a task waits for, in their order
+func (t Tasks) BlockedBy(task Task) Tasks {
+	var blockers Tasks
+	for _, item := range t {
+		if item.Completed == nil && item.UID != "" && containsString(task.DependsOn, item.UID) {
+			blockers = append(blockers, item)
+		}
+	}
+	return blockers
+}
+
+// Blocking fetch the pending tasks that wait for a task, in their order
+func (t Tasks) Blocking(task Task) Tasks {
+	var blocked Tasks
+	if task.UID == "" {
+		return blocked
+	}
+	for _, item := range t {
+		if item.Completed == nil && containsString(item.DependsOn, task.UID) {
+			blocked = append(blocked, item)
+		}
+	}
+	return blocked
+}
+
+// IsBlocked tell whether a task waits for a pending task or has pending
+// subtasks, either way it can not be worked on yet
+func (t Tasks) IsBlocked(task Task) bool {
+	if len(t.BlockedBy(task)) > 0 {
+		return true
+	}
+	for _, item := range t.Subtasks(task) {
+		if item.Completed == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Urgency rank a task by its priority, its due date, the tasks waiting for
+// it and its age, a higher one is more urgent
+func (t Tasks) Urgency(task Task, now time.Time) float64 {
+	urgency := urgencyPriority * float64(task.Priority)
+	if task.Due != nil {
+		// from a fifth two weeks ahead up to the whole weight a week overdue
+		left := task.Due.Sub(now)
+		switch {
+		case left <= -dueWindow/2:
+			urgency += urgencyDue
+		case left >= dueWindow:
+			urgency += urgencyDue * 0.2
+		default:
+			urgency += urgencyDue * (0.2 + 0.8*float64(dueWindow-left)/float64(dueWindow*3/2))
+		}
+	}
+	if len(t.Blocking(task)) > 0 {
+		urgency += urgencyBlocking
+	}
+	age := now.Sub(task.Created)
+	if age > maxAge {
+		age = maxAge
+	}
+	if age > 0 {
+		urgency += urgencyAge * float64(age) / float64(maxAge)
+	}
+	return urgency
+}
+
+// GetNextTasks fetch the pending tasks that are not blocked, the most
+// urgent first
+func (t Tasks) GetNextTasks(now time.Time) Tasks {
+	var next Tasks
+	for _, item := range t {
+		if item.Completed == nil && !t.IsBlocked(item) {
+			next = append(next, item)
+		}
+	}
+	sort.Sort(next)
+	urgency := make(map[int]float64, len(next))
+	for _, item := range next {
+		urgency[item.Id] = t.Urgency(item, now)
+	}
+	sort.SliceStable(next, func(i, j int) bool { return urgency[next[i].Id] > urgency[next[j].Id] })
+	return next
+}
+
+// tell whether the task at index i waits for the task at index target,
+// directly or through other tasks
+func (t Tasks) dependsOn(i, target int) bool {
+	if t[target].UID == "" {
+		return false
+	}
+	seen := map[string]bool{}
+	pending := append([]string{}, t[i].DependsOn...)
+	for len(pending) > 0 {
+		u := pending[0]
+		pending = pending[1:]
+		if u == t[target].UID {
+			return true
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		for _, item := range t {
+			if item.UID == u {
+				pending = append(pending, item.DependsOn...)
+			}
+		}
+	}
+	return false
+}
+
+// tell whether a list has the item
+func containsString(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
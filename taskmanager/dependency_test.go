@@ -0,0 +1,63 @@
+package taskmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTasks_AddDependency(t *testing.T) {
+	tasks, _ := New(&memoryStorage{tasks: Tasks{{Id: 1, Description: "Deploy"}, {Id: 2, UID: "b", Description: "Test"}, {Id: 3, UID: "c", Description: "Build"}}})
+	task, err := tasks.AddDependency(1, 2)
+	if err != nil || len(task.DependsOn) != 1 || task.DependsOn[0] != "b" {
+		t.Fatal("Failed to add the dependency!", task.DependsOn, err)
+	}
+	if task, _ := tasks.AddDependency(1, 2); len(task.DependsOn) != 1 {
+		t.Error("A dependency must be added once", task.DependsOn)
+	}
+	if _, err := tasks.AddDependency(2, 3); err != nil {
+		t.Fatal("Failed to add the dependency!", err)
+	}
+	for _, on := range [][2]int{{3, 1}, {2, 1}, {1, 1}} {
+		if _, err := tasks.AddDependency(on[0], on[1]); !errors.Is(err, ErrCycle) {
+			t.Error("A cycle must be rejected", on, err)
+		}
+	}
+	if blockers := tasks.BlockedBy(tasks.Tasks[0]); len(blockers) != 1 || blockers[0].Id != 2 {
+		t.Error("Unexpected blockers", blockers)
+	}
+	if blocked := tasks.Blocking(tasks.Tasks[2]); len(blocked) != 1 || blocked[0].Id != 2 {
+		t.Error("Unexpected blocked tasks", blocked)
+	}
+	if task, _ := tasks.RemoveDependency(1, 2); len(task.DependsOn) != 0 {
+		t.Error("Failed to remove the dependency!", task.DependsOn)
+	}
+}
+
+func TestTasks_GetNextTasks(t *testing.T) {
+	now := time.Date(2017, 7, 21, 12, 0, 0, 0, time.UTC)
+	tasks := Tasks{
+		{Id: 1, UID: "a", Created: now, DependsOn: []string{"b"}},
+		{Id: 2, UID: "b", Created: now},
+		{Id: 3, UID: "c", Created: now, Priority: PriorityHigh},
+		{Id: 4, UID: "d", Created: now, Due: timePtr(now.Add(-time.Hour))},
+		{Id: 5, UID: "e", Created: now, Completed: timePtr(now)},
+		{Id: 6, UID: "f", Created: now},
+		{Id: 7, UID: "g", Created: now, Parent: "f"},
+		{Id: 8, UID: "h", Created: now, DependsOn: []string{"e"}},
+	}
+	var ids []int
+	for _, task := range tasks.GetNextTasks(now) {
+		ids = append(ids, task.Id)
+	}
+	// 4 is overdue, 2 blocks 1, 3 has a high priority, 7 blocks its parent
+	expected := []int{4, 2, 3, 8, 7}
+	if len(ids) != len(expected) {
+		t.Fatal("Unexpected next tasks", ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatal("Unexpected next tasks", ids)
+		}
+	}
+}
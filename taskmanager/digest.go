@@ -0,0 +1,75 @@
+package taskmanager
+
+import (
+	"sort"
+	"time"
+)
+
+// Digest is the daily summary of the task list, the days are read in the
+// location of its Date
+type Digest struct {
+	Date time.Time
+	// New are the pending tasks created in the last 24 hours
+	New Tasks
+	// DueToday are the pending tasks due today or with a reminder today
+	DueToday Tasks
+	// Overdue are the pending tasks past their due date, the most overdue first
+	Overdue Tasks
+	// CompletedYesterday are the tasks completed the day before Date
+	CompletedYesterday Tasks
+}
+
+// Digest summarize the task list for the day of now
+func (t Tasks) Digest(now time.Time) Digest {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow, yesterday := today.AddDate(0, 0, 1), today.AddDate(0, 0, -1)
+	within := func(at time.Time, from, to time.Time) bool {
+		return !at.Before(from) && at.Before(to)
+	}
+	d := Digest{Date: today, Overdue: t.GetOverdueTasks(now)}
+	for _, item := range t.GetPendingTasks() {
+		if item.Created.After(now.Add(-24 * time.Hour)) {
+			d.New = append(d.New, item)
+		}
+		if item.Due != nil && !item.Due.Before(now) && item.Due.Before(tomorrow) {
+			d.DueToday = append(d.DueToday, item)
+		}
+	}
+	// the time of a task today, its due date or else its first reminder today
+	todayAt := func(task Task) (time.Time, bool) {
+		if task.Due != nil && within(*task.Due, today, tomorrow) {
+			return *task.Due, true
+		}
+		var first time.Time
+		for _, alarm := range task.Alarms() {
+			if within(alarm.At, today, tomorrow) && (first.IsZero() || alarm.At.Before(first)) {
+				first = alarm.At
+			}
+		}
+		return first, !first.IsZero()
+	}
+	for _, item := range t.GetReminderTasks() {
+		if item.Due != nil && within(*item.Due, today, tomorrow) {
+			continue
+		}
+		if _, ok := todayAt(item); ok {
+			d.DueToday = append(d.DueToday, item)
+		}
+	}
+	sort.SliceStable(d.DueToday, func(i, j int) bool {
+		a, _ := todayAt(d.DueToday[i])
+		b, _ := todayAt(d.DueToday[j])
+		return a.Before(b)
+	})
+	for _, item := range t.GetCompletedTasks() {
+		if within(*item.Completed, yesterday, today) {
+			d.CompletedYesterday = append(d.CompletedYesterday, item)
+		}
+	}
+	return d
+}
+
+// Empty report whether there is nothing to summarize
+func (d Digest) Empty() bool {
+	return len(d.New) == 0 && len(d.DueToday) == 0 && len(d.Overdue) == 0 && len(d.CompletedYesterday) == 0
+}
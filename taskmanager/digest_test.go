@@ -0,0 +1,36 @@
+package taskmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTasks_Digest(t *testing.T) {
+	now := time.Date(2017, 7, 21, 12, 0, 0, 0, time.UTC)
+	at := func(hours time.Duration) *time.Time { return timePtr(now.Add(hours * time.Hour)) }
+	tasks, _ := New(&memoryStorage{tasks: Tasks{
+		{Id: 1, Description: "Added this morning", Created: now.Add(-3 * time.Hour)},
+		{Id: 2, Description: "Due tonight", Created: now.AddDate(0, 0, -7), Due: at(8)},
+		{Id: 3, Description: "Reminded this afternoon", Created: now.AddDate(0, 0, -7), RemindAt: at(2), Due: at(48)},
+		{Id: 4, Description: "Overdue", Created: now.AddDate(0, 0, -7), Due: at(-30)},
+		{Id: 5, Description: "Done yesterday", Created: now.AddDate(0, 0, -7), Completed: at(-20)},
+		{Id: 6, Description: "Done today", Created: now.AddDate(0, 0, -7), Completed: at(-1)},
+		{Id: 7, Description: "Due tomorrow", Created: now.AddDate(0, 0, -7), Due: at(20)},
+	}})
+	d := tasks.Digest(now)
+	if len(d.New) != 1 || d.New[0].Id != 1 {
+		t.Error("Failed to get the new tasks!", d.New)
+	}
+	if len(d.DueToday) != 2 || d.DueToday[0].Id != 3 || d.DueToday[1].Id != 2 {
+		t.Error("Failed to get the tasks of today, the earliest first!", d.DueToday)
+	}
+	if len(d.Overdue) != 1 || d.Overdue[0].Id != 4 {
+		t.Error("Failed to get the overdue tasks!", d.Overdue)
+	}
+	if len(d.CompletedYesterday) != 1 || d.CompletedYesterday[0].Id != 5 {
+		t.Error("Failed to get the tasks completed yesterday!", d.CompletedYesterday)
+	}
+	if d.Empty() || !(Digest{}).Empty() {
+		t.Error("Failed to tell an empty digest!")
+	}
+}
@@ -0,0 +1,37 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when there is no task with the given id
+	ErrNotFound = errors.New("task not found")
+	// ErrInvalidId is returned for zero or negative ids
+	ErrInvalidId = errors.New("invalid task id")
+	// ErrStorage is returned when the storage can not be read or written
+	ErrStorage = errors.New("storage error")
+	// ErrCorruptDB is returned when the stored data can not be decoded
+	ErrCorruptDB = errors.New("corrupt database")
+	// ErrNoReminder is returned when a reminder action targets a task without reminder
+	ErrNoReminder = errors.New("task has no reminder")
+	// ErrCompleted is returned when a reminder action targets a completed task
+	ErrCompleted = errors.New("task is completed")
+	// ErrOpenSubtasks is returned when a task with pending subtasks is completed
+	ErrOpenSubtasks = errors.New("task has open subtasks")
+	// ErrInvalidParent is returned when a task would become a subtask of itself
+	ErrInvalidParent = errors.New("invalid parent task")
+	// ErrCycle is returned when a dependency would make a task wait for itself
+	ErrCycle = errors.New("dependency cycle")
+)
+
+// wrap a storage failure as ErrStorage, unless it is already one of ours
+func storageError(err error) error {
+	for _, known := range []error{ErrStorage, ErrCorruptDB} {
+		if errors.Is(err, known) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrStorage, err)
+}
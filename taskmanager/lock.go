@@ -0,0 +1,20 @@
+package taskmanager
+
+import "os"
+
+// lockFile open (or create) the lock file and block until an exclusive
+// advisory lock is held on it, the returned func releases the lock
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		funlock(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,68 @@
+package taskmanager
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const (
+	lockProcesses    = 4
+	lockTasksPerProc = 25
+)
+
+// TestLock_helperProcess is run by TestLock_concurrentProcesses in a child process
+func TestLock_helperProcess(t *testing.T) {
+	if os.Getenv("TASK_LOCK_HELPER") != "1" {
+		t.Skip("only run as a child process")
+	}
+	tasks, err := New(NewFileStorage(os.Getenv("TASK_LOCK_DB")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < lockTasksPerProc; i++ {
+		if _, err := tasks.Add(fmt.Sprintf("task %s-%d", os.Getenv("TASK_LOCK_PROC"), i), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLock_concurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping multi process test in short mode")
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tasks.json")
+	var procs []*exec.Cmd
+	for i := 0; i < lockProcesses; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLock_helperProcess$")
+		cmd.Env = append(os.Environ(), "TASK_LOCK_HELPER=1", "TASK_LOCK_PROC="+strconv.Itoa(i), "TASK_LOCK_DB="+path, "TASK_DB_FILE_PATH="+dir)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		procs = append(procs, cmd)
+	}
+	for _, cmd := range procs {
+		if err := cmd.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks, err := NewFileStorage(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks.TotalTask() != lockProcesses*lockTasksPerProc {
+		t.Errorf("Lost writes: expected %d tasks, got %d", lockProcesses*lockTasksPerProc, tasks.TotalTask())
+	}
+	ids := map[int]bool{}
+	for _, task := range tasks {
+		if ids[task.Id] {
+			t.Errorf("Duplicate task id %d", task.Id)
+		}
+		ids[task.Id] = true
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package taskmanager
+
+import (
+	"os"
+	"syscall"
+)
+
+// flock take an exclusive lock on the file
+func flock(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// funlock release the lock on the file
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
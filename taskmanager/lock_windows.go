@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package taskmanager
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// flock take an exclusive lock on the first byte of the file
+func flock(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+}
+
+// funlock release the lock on the file
+func funlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,110 @@
+package taskmanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Priority is how important a task is, the zero value has none
+type Priority int
+
+// the priority levels, a higher one sorts first
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+// SortOrder is how GetAllTasks and GetPendingTasks order the tasks
+type SortOrder string
+
+const (
+	// SortByID lists the newest task first
+	SortByID SortOrder = "id"
+	// SortByPriority lists the highest priority first, then the earliest
+	// due date, then the newest task
+	SortByPriority SortOrder = "priority"
+)
+
+// DefaultSort is the order of GetAllTasks and GetPendingTasks
+var DefaultSort = SortByPriority
+
+// ParsePriority read a priority like "high", "H", "medium", "m", "low", "l" or "none"
+func ParsePriority(value string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "h", "high":
+		return PriorityHigh, nil
+	case "m", "med", "medium":
+		return PriorityMedium, nil
+	case "l", "low":
+		return PriorityLow, nil
+	case "", "none":
+		return PriorityNone, nil
+	}
+	return PriorityNone, fmt.Errorf("invalid priority %q, use high, medium, low or none", value)
+}
+
+// String return the name of the priority, empty when there is none
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityMedium:
+		return "medium"
+	case PriorityLow:
+		return "low"
+	}
+	return ""
+}
+
+// ParseSort read a sort order, "id" or "priority"
+func ParseSort(value string) (SortOrder, error) {
+	switch order := SortOrder(strings.ToLower(strings.TrimSpace(value))); order {
+	case SortByID, SortByPriority:
+		return order, nil
+	}
+	return "", fmt.Errorf("invalid sort order %q, use id or priority", value)
+}
+
+// UpdateTaskPriority set the priority of a task by id
+func (l *List) UpdateTaskPriority(id int, priority Priority) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	l.Tasks[i].Priority = priority
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+// sort the tasks in the DefaultSort order
+func (t Tasks) sortDefault() {
+	sort.Sort(t)
+	if DefaultSort != SortByPriority {
+		return
+	}
+	sort.SliceStable(t, func(i, j int) bool {
+		a, b := t[i], t[j]
+		switch {
+		case a.Priority != b.Priority:
+			return a.Priority > b.Priority
+		case a.Due == nil || b.Due == nil:
+			return a.Due != nil && b.Due == nil
+		default:
+			return a.Due.Before(*b.Due)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package taskmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	for value, priority := range map[string]Priority{"high": PriorityHigh, "H": PriorityHigh, "m": PriorityMedium, "Low": PriorityLow, "none": PriorityNone} {
+		if p, err := ParsePriority(value); err != nil || p != priority {
+			t.Errorf("ParsePriority(%q) = %v, %v expected %v", value, p, err, priority)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("Unknown priority must fail")
+	}
+}
+
+func TestTasks_sortDefault(t *testing.T) {
+	defer func(order SortOrder) { DefaultSort = order }(DefaultSort)
+	now := time.Now()
+	tasks := Tasks{
+		{Id: 1, Description: "Old low"},
+		{Id: 2, Description: "High", Priority: PriorityHigh},
+		{Id: 3, Description: "Due later", Due: timePtr(now.Add(48 * time.Hour))},
+		{Id: 4, Description: "Due soon", Due: timePtr(now.Add(time.Hour))},
+		{Id: 5, Description: "New low"},
+		{Id: 6, Description: "Medium due", Priority: PriorityMedium, Due: timePtr(now)},
+	}
+	DefaultSort = SortByPriority
+	expect := func(ids ...int) {
+		t.Helper()
+		for i, id := range ids {
+			if tasks[i].Id != id {
+				t.Fatalf("Task %d sorted at %d, expected %d", tasks[i].Id, i, id)
+			}
+		}
+	}
+	tasks.GetAllTasks()
+	expect(2, 6, 4, 3, 5, 1)
+	DefaultSort = SortByID
+	tasks.GetAllTasks()
+	expect(6, 5, 4, 3, 2, 1)
+}
+
+func TestTasks_UpdateTaskPriority(t *testing.T) {
+	tasks, _ := New(&memoryStorage{tasks: Tasks{{Id: 1, Description: "Go to store"}}})
+	task, err := tasks.UpdateTaskPriority(1, PriorityHigh)
+	if err != nil || task.Priority != PriorityHigh || task.Updated == nil {
+		t.Error("Failed to update the priority!", err)
+	}
+	if _, err := tasks.UpdateTaskPriority(2, PriorityLow); err == nil {
+		t.Error("Updating the priority of a missing task must fail")
+	}
+}
@@ -0,0 +1,106 @@
+package taskmanager
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// projectPattern is a project name, dotted names like work.backend nest
+var projectPattern = regexp.MustCompile(`^[\pL\pN_-]+(\.[\pL\pN_-]+)*$`)
+
+// ProjectCount is how many tasks a project has, its nested projects included
+type ProjectCount struct {
+	Project string
+	Pending int
+	Total   int
+}
+
+// ParseProject validate a project name and return it in lower case, an
+// empty name or "none" is no project
+func ParseProject(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "none" {
+		return "", nil
+	}
+	if !projectPattern.MatchString(name) {
+		return "", fmt.Errorf("invalid project %q, use names like work or work.backend", name)
+	}
+	return name, nil
+}
+
+// InProject tell whether a task belongs to the project or one nested in it
+func (task Task) InProject(project string) bool {
+	return task.Project == project || strings.HasPrefix(task.Project, project+".")
+}
+
+// FilterProject keep the tasks of a project and the projects nested in it,
+// in their order
+func (t Tasks) FilterProject(project string) Tasks {
+	var projectTasks Tasks
+	for _, item := range t {
+		if item.InProject(project) {
+			projectTasks = append(projectTasks, item)
+		}
+	}
+	return projectTasks
+}
+
+// GetProjects count the tasks of every project by name, a project counts
+// the tasks of the projects nested in it as well
+func (t Tasks) GetProjects() []ProjectCount {
+	counts := map[string]*ProjectCount{}
+	for _, item := range t {
+		if item.Project == "" {
+			continue
+		}
+		// work.backend counts for work.backend and work
+		parts := strings.Split(item.Project, ".")
+		for n := range parts {
+			name := strings.Join(parts[:n+1], ".")
+			c, ok := counts[name]
+			if !ok {
+				c = &ProjectCount{Project: name}
+				counts[name] = c
+			}
+			c.Total++
+			if item.Completed == nil {
+				c.Pending++
+			}
+		}
+	}
+	var projects []ProjectCount
+	for _, c := range counts {
+		projects = append(projects, *c)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Project < projects[j].Project })
+	return projects
+}
+
+// MoveTask move a task into a project by id, an empty project takes it
+// out of every project
+func (l *List) MoveTask(id int, project string) (Task, error) {
+	project, err := ParseProject(project)
+	if err != nil {
+		return Task{}, err
+	}
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	l.Tasks[i].Project = project
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
@@ -0,0 +1,54 @@
+package taskmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProject(t *testing.T) {
+	for name, project := range map[string]string{"Work": "work", " work.Backend ": "work.backend", "none": "", "": ""} {
+		if p, err := ParseProject(name); err != nil || p != project {
+			t.Errorf("ParseProject(%q) = %q, %v expected %q", name, p, err, project)
+		}
+	}
+	for _, name := range []string{"work.", ".work", "work..backend", "my work"} {
+		if _, err := ParseProject(name); err == nil {
+			t.Errorf("ParseProject(%q) must fail", name)
+		}
+	}
+}
+
+func TestTasks_GetProjects(t *testing.T) {
+	tasks := Tasks{
+		{Id: 1, Project: "work"},
+		{Id: 2, Project: "work.backend"},
+		{Id: 3, Project: "work.backend", Completed: timeAt("2017-07-21T12:13:00Z")},
+		{Id: 4, Project: "workshop"},
+		{Id: 5},
+	}
+	expected := []ProjectCount{
+		{Project: "work", Pending: 2, Total: 3},
+		{Project: "work.backend", Pending: 1, Total: 2},
+		{Project: "workshop", Pending: 1, Total: 1},
+	}
+	if projects := tasks.GetProjects(); !reflect.DeepEqual(projects, expected) {
+		t.Error("Unexpected project counts", projects)
+	}
+	if work := tasks.FilterProject("work"); len(work) != 3 || work[2].Id != 3 {
+		t.Error("Project must hold its nested projects only", work)
+	}
+}
+
+func TestTasks_MoveTask(t *testing.T) {
+	tasks, _ := New(&memoryStorage{tasks: Tasks{{Id: 1, Description: "Fix login", Project: "work"}}})
+	task, err := tasks.MoveTask(1, "Personal.Home")
+	if err != nil || task.Project != "personal.home" {
+		t.Error("Failed to move the task!", task.Project, err)
+	}
+	if _, err := tasks.MoveTask(1, "personal home"); err == nil {
+		t.Error("Invalid project must fail")
+	}
+	if task, _ := tasks.MoveTask(1, "none"); task.Project != "" {
+		t.Error("Failed to take the task out of its project!")
+	}
+}
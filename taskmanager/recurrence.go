@@ -0,0 +1,201 @@
+package taskmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+var (
+	// weekdays by their full and short english names
+	weekdays = map[string]string{
+		"monday": "MO", "mon": "MO", "tuesday": "TU", "tue": "TU", "wednesday": "WE", "wed": "WE",
+		"thursday": "TH", "thu": "TH", "friday": "FR", "fri": "FR", "saturday": "SA", "sat": "SA",
+		"sunday": "SU", "sun": "SU",
+	}
+	// frequencies by the words that name them
+	frequencies = map[string]string{
+		"day": "DAILY", "days": "DAILY", "daily": "DAILY",
+		"week": "WEEKLY", "weeks": "WEEKLY", "weekly": "WEEKLY",
+		"month": "MONTHLY", "months": "MONTHLY", "monthly": "MONTHLY",
+		"year": "YEARLY", "years": "YEARLY", "yearly": "YEARLY", "annually": "YEARLY",
+	}
+	// a time of day like 5pm, 9:30am or 17:00
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	// a day of the month like 1st or 15th
+	monthDayPattern = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)$`)
+	// the COUNT part of a RRULE
+	countPattern = regexp.MustCompile(`COUNT=\d+`)
+)
+
+// ParseEvery convert a schedule like "friday 5pm", "weekdays 9:30am",
+// "other week", "month 1st" or "2 days" to an iCalendar RRULE such as
+// FREQ=WEEKLY;BYDAY=FR;BYHOUR=17;BYMINUTE=0;BYSECOND=0. A schedule that
+// already is a RRULE is only validated.
+func ParseEvery(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if rule := strings.TrimPrefix(strings.ToUpper(text), "RRULE:"); strings.HasPrefix(rule, "FREQ=") {
+		return rule, validRule(rule)
+	}
+	var (
+		freq, interval    string
+		byDay, byMonthDay []string
+		byHour, byMinute  string
+	)
+	fields := strings.Fields(strings.NewReplacer(",", " ").Replace(strings.ToLower(text)))
+	for _, field := range fields {
+		switch {
+		case field == "every" || field == "on" || field == "at" || field == "and" || field == "the" || field == "of":
+		case field == "other":
+			interval = "2"
+		case field == "weekday" || field == "weekdays":
+			freq = "WEEKLY"
+			byDay = append(byDay, "MO", "TU", "WE", "TH", "FR")
+		case weekdays[field] != "":
+			freq = "WEEKLY"
+			byDay = append(byDay, weekdays[field])
+		case frequencies[field] != "":
+			if freq == "" || len(byDay) == 0 && len(byMonthDay) == 0 {
+				freq = frequencies[field]
+			}
+		case monthDayPattern.MatchString(field):
+			if freq != "YEARLY" {
+				freq = "MONTHLY"
+			}
+			byMonthDay = append(byMonthDay, monthDayPattern.FindStringSubmatch(field)[1])
+		case clockPattern.MatchString(field) && strings.ContainsAny(field, ":apm"):
+			m := clockPattern.FindStringSubmatch(field)
+			hour, _ := strconv.Atoi(m[1])
+			switch {
+			case m[3] == "pm" && hour < 12:
+				hour += 12
+			case m[3] == "am" && hour == 12:
+				hour = 0
+			}
+			byHour, byMinute = strconv.Itoa(hour), "0"
+			if m[2] != "" {
+				minute, _ := strconv.Atoi(m[2])
+				byMinute = strconv.Itoa(minute)
+			}
+		case clockPattern.MatchString(field) && interval == "":
+			interval = field
+		default:
+			return "", fmt.Errorf("can not read %q in schedule %q", field, text)
+		}
+	}
+	if freq == "" {
+		return "", fmt.Errorf("schedule %q has no day, week, month, year or weekday", text)
+	}
+	rule := []string{"FREQ=" + freq}
+	if interval != "" && interval != "1" {
+		rule = append(rule, "INTERVAL="+interval)
+	}
+	if len(byMonthDay) > 0 {
+		rule = append(rule, "BYMONTHDAY="+strings.Join(byMonthDay, ","))
+	}
+	if len(byDay) > 0 {
+		rule = append(rule, "BYDAY="+strings.Join(byDay, ","))
+	}
+	if byHour != "" {
+		rule = append(rule, "BYHOUR="+byHour, "BYMINUTE="+byMinute, "BYSECOND=0")
+	}
+	return strings.Join(rule, ";"), validRule(strings.Join(rule, ";"))
+}
+
+// NextOccurrence return the first time of a RRULE after the given time,
+// counted from start and in its timezone. It is false when the rule ended.
+func NextOccurrence(rule string, start, after time.Time) (time.Time, bool, error) {
+	r, err := newRule(rule, start)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	next := r.After(after, false)
+	return next, !next.IsZero(), nil
+}
+
+// the rule of the occurrence at next, each instance counts the series from
+// its own due date so a COUNT is reduced by the occurrences before next
+func ruleFrom(rule string, start, next time.Time) (string, error) {
+	r, err := newRule(rule, start)
+	if err != nil || r.OrigOptions.Count == 0 {
+		return rule, err
+	}
+	count := r.OrigOptions.Count - (len(r.Between(start, next, true)) - 1)
+	return countPattern.ReplaceAllString(rule, "COUNT="+strconv.Itoa(count)), nil
+}
+
+// a RRULE counted from start and in its timezone
+func newRule(rule string, start time.Time) (*rrule.RRule, error) {
+	option, err := rrule.StrToROptionInLocation(rule, start.Location())
+	if err != nil {
+		return nil, err
+	}
+	option.Dtstart = start
+	return rrule.NewRRule(*option)
+}
+
+// validate a RRULE without DTSTART
+func validRule(rule string) error {
+	if _, err := rrule.StrToRRule(rule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", rule, err)
+	}
+	return nil
+}
+
+// nextInstance return a copy of a recurring task for its next occurrence,
+// the reminders keep their distance to the due date. It is false when the
+// task does not recur anymore.
+func (task Task) nextInstance(id int, completed time.Time) (Task, bool) {
+	if task.Recurrence == "" {
+		return Task{}, false
+	}
+	due := task.Created
+	if task.Due != nil {
+		due = *task.Due
+	}
+	// occurrences that passed before the task was completed are skipped,
+	// the rule is read in local time so "friday 5pm" follows the clock
+	after := due
+	if completed.After(after) {
+		after = completed
+	}
+	next, ok, err := NextOccurrence(task.Recurrence, due.In(time.Local), after)
+	if err != nil || !ok {
+		return Task{}, false
+	}
+	rule, err := ruleFrom(task.Recurrence, due.In(time.Local), next)
+	if err != nil {
+		return Task{}, false
+	}
+	next = next.UTC()
+	shift := next.Sub(due)
+	instance := Task{
+		Id:          id,
+		UID:         uid(),
+		Description: task.Description,
+		Tags:        task.Tags,
+		Created:     completed,
+		Due:         &next,
+		RepeatEvery: task.RepeatEvery,
+		Recurrence:  rule,
+		Priority:    task.Priority,
+		Project:     task.Project,
+		Parent:      task.Parent,
+		DependsOn:   task.DependsOn,
+	}
+	if task.RemindAt != nil {
+		instance.RemindAt = timePtr(task.RemindAt.Add(shift))
+	}
+	for _, r := range task.Reminders {
+		if r.At != nil {
+			r.At = timePtr(r.At.Add(shift))
+		}
+		r.NotifiedAt = nil
+		instance.Reminders = append(instance.Reminders, r)
+	}
+	return instance, true
+}
@@ -0,0 +1,96 @@
+package taskmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEvery(t *testing.T) {
+	for text, rule := range map[string]string{
+		"friday 5pm":                  "FREQ=WEEKLY;BYDAY=FR;BYHOUR=17;BYMINUTE=0;BYSECOND=0",
+		"every day at 9:30am":         "FREQ=DAILY;BYHOUR=9;BYMINUTE=30;BYSECOND=0",
+		"weekdays 09:15":              "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=9;BYMINUTE=15;BYSECOND=0",
+		"every other week":            "FREQ=WEEKLY;INTERVAL=2",
+		"month on the 1st":            "FREQ=MONTHLY;BYMONTHDAY=1",
+		"2 days":                      "FREQ=DAILY;INTERVAL=2",
+		"mon, wed and fri 12am":       "FREQ=WEEKLY;BYDAY=MO,WE,FR;BYHOUR=0;BYMINUTE=0;BYSECOND=0",
+		"RRULE:FREQ=YEARLY;BYMONTH=4": "FREQ=YEARLY;BYMONTH=4",
+	} {
+		got, err := ParseEvery(text)
+		if err != nil || got != rule {
+			t.Errorf("ParseEvery(%q) = %q, %v expected %q", text, got, err, rule)
+		}
+	}
+	for _, text := range []string{"", "5pm", "sometimes", "FREQ=HOURLY;BYDAY=XX"} {
+		if _, err := ParseEvery(text); err == nil {
+			t.Errorf("ParseEvery(%q) must fail", text)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	start := time.Date(2017, 7, 21, 17, 0, 0, 0, time.UTC)
+	next, ok, err := NextOccurrence("FREQ=WEEKLY;BYDAY=FR;BYHOUR=17;BYMINUTE=0;BYSECOND=0", start, start)
+	if err != nil || !ok || !next.Equal(start.AddDate(0, 0, 7)) {
+		t.Error("Failed to get the next friday", next, err)
+	}
+	until := "FREQ=DAILY;UNTIL=20170722T000000Z"
+	if _, ok, _ := NextOccurrence(until, start, start); ok {
+		t.Error("Ended rule must not have a next occurrence")
+	}
+}
+
+func TestTasks_MarkAsCompleteTask_recurringCount(t *testing.T) {
+	due := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	ms := &memoryStorage{tasks: Tasks{{Id: 1, UID: "a", Description: "Take the pills", Due: timePtr(due), Recurrence: "FREQ=DAILY;COUNT=2"}}}
+	tasks, _ := New(ms)
+	if _, err := tasks.MarkAsCompleteTask(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Tasks) != 2 || tasks.Tasks[1].Recurrence != "FREQ=DAILY;COUNT=1" {
+		t.Fatal("Next occurrence must count the rest of the series!", tasks.Tasks)
+	}
+	tasks.MarkAsCompleteTask(2)
+	if len(tasks.Tasks) != 2 {
+		t.Error("Series must end after COUNT occurrences!", tasks.Tasks)
+	}
+}
+
+func TestTasks_MarkAsCompleteTask_recurring(t *testing.T) {
+	due := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	ms := &memoryStorage{tasks: Tasks{{
+		Id: 1, UID: "a", Description: "Submit timesheet", Tags: []string{"work"}, Due: timePtr(due),
+		RemindAt: timePtr(due), NotifiedAt: timePtr(due), Reminders: []Reminder{{Before: 60, NotifiedAt: timePtr(due)}},
+		Recurrence: "FREQ=DAILY",
+	}}}
+	tasks, _ := New(ms)
+	if _, err := tasks.MarkAsCompleteTask(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Tasks) != 2 {
+		t.Fatal("Completing a recurring task must add its next occurrence!")
+	}
+	next := tasks.Tasks[1]
+	if next.Id != 2 || next.UID == "" || next.UID == "a" || next.Completed != nil || !next.HasTag("work") {
+		t.Error("Next occurrence must be a new pending task!", next)
+	}
+	if !next.Due.Equal(due.AddDate(0, 0, 1)) || !next.RemindAt.Equal(*next.Due) {
+		t.Error("Next occurrence must be due and reminded a day later!", next.Due)
+	}
+	if next.NotifiedAt != nil || next.Reminders[0].NotifiedAt != nil || len(next.Alarms()) != 2 {
+		t.Error("Reminders of the next occurrence must be scheduled again!")
+	}
+	// completing an already completed task does not add another one
+	tasks.MarkAsCompleteTask(1)
+	if len(tasks.Tasks) != 2 {
+		t.Error("Next occurrence was added twice!")
+	}
+	task, err := tasks.UpdateTaskRecurrence(2, "")
+	if err != nil || task.Recurrence != "" {
+		t.Fatal("Failed to stop the recurrence", err)
+	}
+	tasks.MarkAsCompleteTask(2)
+	if len(tasks.Tasks) != 2 {
+		t.Error("Task that stopped recurring must not add an occurrence!")
+	}
+}
@@ -0,0 +1,291 @@
+package taskmanager
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+const (
+	// maxSleep caps how long the scheduler sleeps, timers do not advance while
+	// the machine is suspended so the wall clock is checked at least this often
+	maxSleep = time.Minute
+	// retryDelay is how long a reminder that failed to fire waits for the
+	// first retry, the delay doubles on every failure up to maxSleep
+	retryDelay = 5 * time.Second
+)
+
+type (
+	// Clock is the time source of the scheduler, tests inject a fake one
+	Clock interface {
+		Now() time.Time
+		After(d time.Duration) <-chan time.Time
+	}
+
+	// Scheduler keeps the upcoming reminders in a min-heap and sleeps until
+	// the next one is due, the task list is only reloaded when the storage
+	// changes or Reload is called. Reload, Pause, Resume and Scheduled are
+	// safe to call while it runs.
+	Scheduler struct {
+		// Fire deliver a due reminder, the delivery is recorded when it returns nil
+		Fire func(alarm Alarm) error
+		// Error receive the failures that do not stop the scheduler, optional
+		Error func(err error)
+		// Clock is the time source, the wall clock when nil
+		Clock Clock
+
+		storage Storage
+		tasks   *List
+		wake    chan struct{}
+
+		// retries hold the back off of the alarms that failed to fire, only
+		// the Run goroutine uses them
+		retries map[alarmKey]retry
+
+		mu     sync.Mutex
+		queue  reminderQueue
+		paused bool
+	}
+
+	// queuedAlarm is an alarm with the time it is fired at, which is later
+	// than the alarm's own time when firing it failed before
+	queuedAlarm struct {
+		Alarm
+		due time.Time
+	}
+
+	// reminderQueue is a min-heap of alarms ordered by the time they are fired at
+	reminderQueue []queuedAlarm
+
+	// alarmKey identifies an alarm across reloads
+	alarmKey struct {
+		id, index int
+	}
+
+	// retry is the back off of an alarm that failed to fire
+	retry struct {
+		due   time.Time
+		delay time.Duration
+	}
+
+	// wallClock is the real time
+	wallClock struct{}
+)
+
+// NewScheduler return a scheduler for the reminders of a storage
+func NewScheduler(s Storage, fire func(alarm Alarm) error) *Scheduler {
+	return &Scheduler{Fire: fire, storage: s, wake: make(chan struct{}, 1)}
+}
+
+// Run fire the reminders until done is closed, overdue reminders that were
+// never notified are fired right away
+func (s *Scheduler) Run(done <-chan struct{}) error {
+	if s.Clock == nil {
+		s.Clock = wallClock{}
+	}
+	if s.wake == nil {
+		s.wake = make(chan struct{}, 1)
+	}
+	changes, err := s.storage.Watch(done)
+	if err != nil {
+		return err
+	}
+	if err := s.reload(); err != nil {
+		return err
+	}
+	for {
+		s.fireDue()
+		sleep := maxSleep
+		s.mu.Lock()
+		if s.queue.Len() > 0 && !s.paused {
+			if next := s.queue[0].due.Sub(s.Clock.Now()); next < sleep {
+				sleep = next
+			}
+		}
+		s.mu.Unlock()
+		select {
+		case <-done:
+			return nil
+		case <-s.Clock.After(sleep):
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+			}
+			if err := s.reload(); err != nil {
+				s.error(err)
+			}
+		case <-s.wake:
+			if err := s.reload(); err != nil {
+				s.error(err)
+			}
+		}
+	}
+}
+
+// Reload make the running scheduler read the tasks again right away
+func (s *Scheduler) Reload() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pause hold the reminders back, the ones due meanwhile fire on Resume
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+// Resume fire the reminders again, starting with the ones held back
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.mu.Unlock()
+	s.Reload()
+}
+
+// Paused tell whether the reminders are held back
+func (s *Scheduler) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Scheduled return the alarms waiting to be fired, the next one first
+func (s *Scheduler) Scheduled() []Alarm {
+	s.mu.Lock()
+	queue := append(reminderQueue{}, s.queue...)
+	s.mu.Unlock()
+	var alarms []Alarm
+	for queue.Len() > 0 {
+		alarms = append(alarms, heap.Pop(&queue).(queuedAlarm).Alarm)
+	}
+	return alarms
+}
+
+// reload the task list and rebuild the queue of reminders
+func (s *Scheduler) reload() error {
+	tasks, err := New(s.storage)
+	if err != nil {
+		return err
+	}
+	s.tasks = tasks
+	var queue reminderQueue
+	retries := map[alarmKey]retry{}
+	for _, alarm := range tasks.GetAlarms() {
+		if alarm.NotifiedAt == nil {
+			// a reload must not cut the back off of a failing alarm short
+			if r, ok := s.retries[keyOf(alarm)]; ok {
+				retries[keyOf(alarm)] = r
+			}
+			queue = append(queue, s.queued(alarm))
+		}
+	}
+	s.retries = retries
+	heap.Init(&queue)
+	s.mu.Lock()
+	s.queue = queue
+	s.mu.Unlock()
+	return nil
+}
+
+// fire every reminder that is due, a failed one is retried after a back off
+// and a repeating one is queued again
+func (s *Scheduler) fireDue() {
+	now := s.Clock.Now()
+	var due []Alarm
+	var again []queuedAlarm
+	s.mu.Lock()
+	for !s.paused && s.queue.Len() > 0 && !s.queue[0].due.After(now) {
+		due = append(due, heap.Pop(&s.queue).(queuedAlarm).Alarm)
+	}
+	s.mu.Unlock()
+	for _, alarm := range due {
+		if err := s.Fire(alarm); err != nil {
+			s.error(err)
+			again = append(again, s.backOff(alarm, now))
+			continue
+		}
+		delete(s.retries, keyOf(alarm))
+		if alarm.Index != 0 || alarm.Task.RepeatEvery <= 0 {
+			if _, err := s.tasks.MarkAsNotifiedReminder(alarm.Task.Id, alarm.Index); err != nil {
+				s.error(err)
+			}
+			continue
+		}
+		// a repeating reminder fires again until it is acknowledged
+		task, err := s.tasks.remindAgain(alarm.Task.Id, now.Add(time.Duration(alarm.Task.RepeatEvery)*time.Minute))
+		if err != nil {
+			s.error(err)
+			continue
+		}
+		again = append(again, s.queued(task.Alarms()[0]))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, alarm := range again {
+		heap.Push(&s.queue, alarm)
+	}
+}
+
+// queue an alarm at its own time or, while it is backing off, at its retry
+func (s *Scheduler) queued(alarm Alarm) queuedAlarm {
+	due := alarm.At
+	if r, ok := s.retries[keyOf(alarm)]; ok && r.due.After(due) {
+		due = r.due
+	}
+	return queuedAlarm{Alarm: alarm, due: due}
+}
+
+// hold back an alarm that failed to fire, so a notifier that keeps failing
+// is not called in a busy loop
+func (s *Scheduler) backOff(alarm Alarm, now time.Time) queuedAlarm {
+	if s.retries == nil {
+		s.retries = map[alarmKey]retry{}
+	}
+	r := s.retries[keyOf(alarm)]
+	if r.delay *= 2; r.delay == 0 {
+		r.delay = retryDelay
+	}
+	if r.delay > maxSleep {
+		r.delay = maxSleep
+	}
+	r.due = now.Add(r.delay)
+	s.retries[keyOf(alarm)] = r
+	return queuedAlarm{Alarm: alarm, due: r.due}
+}
+
+// the key of an alarm in the retries
+func keyOf(alarm Alarm) alarmKey {
+	return alarmKey{id: alarm.Task.Id, index: alarm.Index}
+}
+
+// report an error when someone is listening
+func (s *Scheduler) error(err error) {
+	if s.Error != nil {
+		s.Error(err)
+	}
+}
+
+// implement the heap interface
+func (q reminderQueue) Len() int { return len(q) }
+
+func (q reminderQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q reminderQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *reminderQueue) Push(x interface{}) { *q = append(*q, x.(queuedAlarm)) }
+
+func (q *reminderQueue) Pop() interface{} {
+	old := *q
+	alarm := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return alarm
+}
+
+// Now return the current time
+func (wallClock) Now() time.Time { return time.Now() }
+
+// After wait for the duration to elapse
+func (wallClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
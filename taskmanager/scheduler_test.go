@@ -0,0 +1,290 @@
+package taskmanager
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when the test advances it
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	sleeps chan time.Duration
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, sleeps: make(chan time.Duration, 10)}
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) After(d time.Duration) <-chan time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	c := make(chan time.Time, 1)
+	fc.timers = append(fc.timers, fakeTimer{at: fc.now.Add(d), c: c})
+	fc.sleeps <- d
+	return c
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.now = fc.now.Add(d)
+	var pending []fakeTimer
+	for _, t := range fc.timers {
+		if t.at.After(fc.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- fc.now
+	}
+	fc.timers = pending
+}
+
+func TestScheduler(t *testing.T) {
+	start := *timeAt("2017-07-22T10:00:00Z")
+	ms := &memoryStorage{
+		tasks: Tasks{
+			{Id: 1, Description: "Go to store", RemindAt: timeAt("2017-07-22T10:00:20Z")},
+			{Id: 2, Description: "Learn golang testing", RemindAt: timeAt("2017-07-22T10:00:50Z")},
+			{Id: 3, Description: "Meeting with John", RemindAt: timeAt("2017-07-22T09:00:00Z"), NotifiedAt: timeAt("2017-07-22T09:00:00Z")},
+			{Id: 4, Description: "Watch Pirates of the carribean", RemindAt: timeAt("2017-07-22T09:30:00Z")},
+		},
+		changes: make(chan struct{}),
+	}
+	clock := newFakeClock(start)
+	fired := make(chan int, 10)
+	scheduler := NewScheduler(ms, func(alarm Alarm) error {
+		fired <- alarm.Task.Id
+		return nil
+	})
+	scheduler.Clock = clock
+	done := make(chan struct{})
+	stopped := make(chan error)
+	go func() { stopped <- scheduler.Run(done) }()
+
+	expect := func(sleep time.Duration, ids ...int) {
+		t.Helper()
+		if d := <-clock.sleeps; d != sleep {
+			t.Errorf("Scheduler sleeps %v, expected %v", d, sleep)
+		}
+		for _, id := range ids {
+			if got := <-fired; got != id {
+				t.Errorf("Fired reminder %d, expected %d", got, id)
+			}
+		}
+		if len(fired) != 0 {
+			t.Error("Fired unexpected reminders!")
+		}
+	}
+	// the missed reminder fires right away, the notified one never
+	expect(20*time.Second, 4)
+	clock.Advance(20 * time.Second)
+	expect(30*time.Second, 1)
+	// a reminder added by someone else is picked up on change
+	ms.tasks = append(ms.tasks, Task{Id: 5, Description: "Call mom", RemindAt: timeAt("2017-07-22T10:00:30Z")})
+	ms.changes <- struct{}{}
+	expect(10 * time.Second)
+	clock.Advance(10 * time.Second)
+	expect(20*time.Second, 5)
+	// with nothing queued the wall clock is still checked regularly
+	clock.Advance(20 * time.Second)
+	expect(maxSleep, 2)
+	close(done)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range ms.tasks {
+		if task.NotifiedAt == nil {
+			t.Errorf("Delivery of reminder %d was not recorded!", task.Id)
+		}
+	}
+}
+
+func TestScheduler_repeat(t *testing.T) {
+	ms := &memoryStorage{
+		tasks:   Tasks{{Id: 1, Description: "Take the pills", RemindAt: timeAt("2017-07-22T10:00:00Z"), RepeatEvery: 5}},
+		changes: make(chan struct{}),
+	}
+	clock := newFakeClock(*timeAt("2017-07-22T10:00:00Z"))
+	fired := make(chan int, 10)
+	scheduler := NewScheduler(ms, func(alarm Alarm) error {
+		fired <- alarm.Task.Id
+		return nil
+	})
+	scheduler.Clock = clock
+	done := make(chan struct{})
+	stopped := make(chan error)
+	go func() { stopped <- scheduler.Run(done) }()
+
+	// fires right away and again every 5 minutes
+	for i := 0; i < 3; i++ {
+		if d := <-clock.sleeps; d != 5*time.Minute && d != maxSleep {
+			t.Errorf("Scheduler sleeps %v", d)
+		}
+		if len(fired) != 1 {
+			t.Fatalf("Repeating reminder fired %d times, expected once", len(fired))
+		}
+		<-fired
+		clock.Advance(5 * time.Minute)
+	}
+	<-clock.sleeps
+	<-fired
+	// once acknowledged it stops
+	tasks, _ := New(ms)
+	if _, err := tasks.AckTask(1); err != nil {
+		t.Fatal(err)
+	}
+	ms.changes <- struct{}{}
+	if d := <-clock.sleeps; d != maxSleep {
+		t.Error("Acknowledged reminder is still scheduled!")
+	}
+	clock.Advance(5 * time.Minute)
+	<-clock.sleeps
+	if len(fired) != 0 {
+		t.Error("Acknowledged reminder fired again!")
+	}
+	close(done)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScheduler_retry(t *testing.T) {
+	ms := &memoryStorage{tasks: Tasks{{Id: 1, Description: "Go to store", RemindAt: timeAt("2017-07-22T10:00:00Z")}}}
+	clock := newFakeClock(*timeAt("2017-07-22T10:00:00Z"))
+	var mu sync.Mutex
+	attempts, failing := 0, true
+	scheduler := NewScheduler(ms, func(alarm Alarm) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if failing {
+			return errors.New("no notification daemon")
+		}
+		return nil
+	})
+	scheduler.Clock = clock
+	done := make(chan struct{})
+	stopped := make(chan error)
+	go func() { stopped <- scheduler.Run(done) }()
+
+	// a failing reminder is retried with a doubling delay instead of right away
+	for _, delay := range []time.Duration{retryDelay, 2 * retryDelay, 4 * retryDelay, 8 * retryDelay, maxSleep, maxSleep} {
+		if d := <-clock.sleeps; d != delay {
+			t.Errorf("Scheduler sleeps %v, expected %v", d, delay)
+		}
+		clock.Advance(delay)
+	}
+	<-clock.sleeps
+	mu.Lock()
+	if attempts != 7 {
+		t.Errorf("Failing reminder fired %d times, expected 7", attempts)
+	}
+	failing = false
+	mu.Unlock()
+	clock.Advance(maxSleep)
+	<-clock.sleeps
+	close(done)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 8 || ms.tasks[0].NotifiedAt == nil {
+		t.Error("Reminder must be recorded once it fires", attempts)
+	}
+	if len(scheduler.retries) != 0 {
+		t.Error("Delivered reminder must not back off any more!")
+	}
+}
+
+func TestScheduler_multipleReminders(t *testing.T) {
+	ms := &memoryStorage{
+		tasks: Tasks{{
+			Id: 1, Description: "Submit the report", RemindAt: timeAt("2017-07-22T11:00:00Z"),
+			Reminders: []Reminder{{Before: 60}, {At: timeAt("2017-07-22T10:30:00Z")}},
+		}},
+		changes: make(chan struct{}),
+	}
+	clock := newFakeClock(*timeAt("2017-07-22T09:59:00Z"))
+	fired := make(chan int, 10)
+	scheduler := NewScheduler(ms, func(alarm Alarm) error {
+		fired <- alarm.Index
+		return nil
+	})
+	scheduler.Clock = clock
+	done := make(chan struct{})
+	stopped := make(chan error)
+	go func() { stopped <- scheduler.Run(done) }()
+
+	<-clock.sleeps
+	for _, index := range []int{1, 2, 0} {
+		clock.Advance(30 * time.Minute)
+		<-clock.sleeps
+		if got := <-fired; got != index {
+			t.Errorf("Fired reminder %d, expected %d", got, index)
+		}
+		if ms.tasks[0].Completed != nil {
+			t.Error("Firing a reminder must not complete the task!")
+		}
+	}
+	close(done)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if len(scheduler.Scheduled()) != 0 {
+		t.Error("Every reminder must have been fired once!")
+	}
+}
+
+func TestScheduler_control(t *testing.T) {
+	ms := &memoryStorage{tasks: Tasks{{Id: 1, Description: "Go to store", RemindAt: timeAt("2017-07-22T10:00:10Z")}}}
+	clock := newFakeClock(*timeAt("2017-07-22T10:00:00Z"))
+	fired := make(chan int, 10)
+	scheduler := NewScheduler(ms, func(alarm Alarm) error {
+		fired <- alarm.Task.Id
+		return nil
+	})
+	scheduler.Clock = clock
+	done := make(chan struct{})
+	stopped := make(chan error)
+	go func() { stopped <- scheduler.Run(done) }()
+
+	<-clock.sleeps
+	// a paused scheduler holds the due reminder back
+	scheduler.Pause()
+	clock.Advance(10 * time.Second)
+	if d := <-clock.sleeps; d != maxSleep || len(fired) != 0 {
+		t.Error("Paused scheduler must not fire!", d)
+	}
+	// a reload is picked up without a storage change
+	ms.tasks = append(ms.tasks, Task{Id: 2, Description: "Call mom", RemindAt: timeAt("2017-07-22T10:00:30Z")})
+	scheduler.Reload()
+	<-clock.sleeps
+	if len(scheduler.Scheduled()) != 2 || !scheduler.Paused() {
+		t.Error("Failed to reload the paused scheduler!", scheduler.Scheduled())
+	}
+	scheduler.Resume()
+	if d := <-clock.sleeps; d != 20*time.Second {
+		t.Errorf("Scheduler sleeps %v, expected 20s", d)
+	}
+	if got := <-fired; got != 1 || len(fired) != 0 {
+		t.Error("Resumed scheduler must fire the held reminder", got)
+	}
+	close(done)
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+}
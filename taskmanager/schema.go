@@ -0,0 +1,204 @@
+package taskmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// legacyTimeLayout is how created, updated and completed were stored before schema version 2
+	legacyTimeLayout = "Mon, 01/02/06, 03:04PM"
+	// legacyReminderLayout is how remind_at was stored before schema version 2
+	legacyReminderLayout = "2006-01-02 15:04"
+)
+
+type (
+	// document is the on-disk layout of the task database
+	document struct {
+		SchemaVersion int   `json:"schema_version"`
+		Tasks         Tasks `json:"tasks"`
+	}
+
+	// migration upgrade a raw document by a single schema version
+	migration struct {
+		description string
+		migrate     func(doc map[string]interface{}) error
+	}
+
+	// Upgrader is implemented by storages with a versioned schema
+	Upgrader interface {
+		// Upgrade bring the stored data to the current schema version and
+		// return the applied migrations, dryRun only reports them
+		Upgrade(dryRun bool) ([]string, error)
+	}
+)
+
+// migrations is the registry of schema changes, migrations[i] upgrades
+// version i to i+1. Append new ones, never change a released migration.
+var migrations = []migration{
+	{
+		description: "wrap the bare task array in a versioned document",
+		migrate:     func(doc map[string]interface{}) error { return nil },
+	},
+	{
+		description: "store created, updated, remind_at and completed as RFC 3339 times",
+		migrate: func(doc map[string]interface{}) error {
+			return eachTask(doc, legacyTimes)
+		},
+	},
+	{
+		description: "move the single tag into the tags list",
+		migrate: func(doc map[string]interface{}) error {
+			return eachTask(doc, func(task map[string]interface{}) error {
+				if tag, _ := task["tag"].(string); tag != "" {
+					task["tags"] = normalizeTags([]string{tag})
+				}
+				delete(task, "tag")
+				return nil
+			})
+		},
+	},
+}
+
+// schemaVersion is the version written by this package
+var schemaVersion = len(migrations)
+
+// decode a database document of any known version, the tasks are
+// returned in the current layout along with the applied migrations
+func decodeDocument(data []byte) (Tasks, []string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil, nil
+	}
+	if data[0] == '{' {
+		var doc document
+		if err := json.Unmarshal(data, &doc); err == nil && doc.SchemaVersion == schemaVersion {
+			return doc.Tasks, nil, nil
+		}
+	}
+	raw, err := rawDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := upgradeDocument(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	tasks, err := rawTasks(raw)
+	return tasks, applied, err
+}
+
+// encode the tasks as a document of the current version
+func encodeDocument(tasks Tasks) ([]byte, error) {
+	if tasks == nil {
+		tasks = Tasks{}
+	}
+	return json.Marshal(document{SchemaVersion: schemaVersion, Tasks: tasks})
+}
+
+// parse a document without a schema, a bare array is the version 0 layout
+func rawDocument(data []byte) (map[string]interface{}, error) {
+	if data[0] == '[' {
+		var tasks []interface{}
+		if err := json.Unmarshal(data, &tasks); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"schema_version": 0, "tasks": tasks}, nil
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// run the migrations a raw document needs to reach the current version
+func upgradeDocument(doc map[string]interface{}) ([]string, error) {
+	version := 0
+	switch v := doc["schema_version"].(type) {
+	case int:
+		version = v
+	case float64:
+		version = int(v)
+	}
+	if version > schemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than the supported version %d", version, schemaVersion)
+	}
+	var applied []string
+	for v := version; v < schemaVersion; v++ {
+		if err := migrations[v].migrate(doc); err != nil {
+			return applied, fmt.Errorf("migration to schema version %d failed: %w", v+1, err)
+		}
+		doc["schema_version"] = v + 1
+		applied = append(applied, fmt.Sprintf("v%d -> v%d: %s", v, v+1, migrations[v].description))
+	}
+	return applied, nil
+}
+
+// convert the tasks of a raw document to Tasks
+func rawTasks(doc map[string]interface{}) (Tasks, error) {
+	data, err := json.Marshal(doc["tasks"])
+	if err != nil {
+		return nil, err
+	}
+	var tasks Tasks
+	err = json.Unmarshal(data, &tasks)
+	return tasks, err
+}
+
+// eachTask run fn on every raw task of a document, a helper for migrations
+func eachTask(doc map[string]interface{}, fn func(task map[string]interface{}) error) error {
+	tasks, _ := doc["tasks"].([]interface{})
+	for _, t := range tasks {
+		task, ok := t.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected task %v", t)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert the times of a raw task from the legacy layouts, a created or
+// updated time that can not be parsed means the database is corrupt, an
+// unreadable reminder is dropped rather than fired right away and an
+// unreadable completion becomes the current time, so a completed task stays
+// completed
+func legacyTimes(task map[string]interface{}) error {
+	for _, field := range []string{"created", "updated"} {
+		t, err := legacyTime(task[field], legacyTimeLayout)
+		if err != nil {
+			return fmt.Errorf("%w: task %v: %s: %v", ErrCorruptDB, task["id"], field, err)
+		}
+		task[field] = t
+	}
+	var err error
+	if task["remind_at"], err = legacyTime(task["remind_at"], legacyReminderLayout); err != nil {
+		task["remind_at"] = nil
+	}
+	if task["completed"], err = legacyTime(task["completed"], legacyTimeLayout); err != nil {
+		task["completed"] = now().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// convert a time formatted with a legacy layout in local time to RFC 3339,
+// an empty value becomes null
+func legacyTime(value interface{}, layout string) (interface{}, error) {
+	s, _ := value.(string)
+	if s == "" {
+		return nil, nil
+	}
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return s, nil
+	}
+	t, err := time.ParseInLocation(layout, s, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(time.RFC3339), nil
+}
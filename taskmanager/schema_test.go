@@ -0,0 +1,134 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeDocument_bareArray(t *testing.T) {
+	tasks, applied, err := decodeDocument([]byte(`[{"id":1,"uid":"a","description":"Go to store"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Description != "Go to store" {
+		t.Error("Failed to decode version 0 document!")
+	}
+	if len(applied) != schemaVersion {
+		t.Error("Every migration must be applied to a version 0 document!")
+	}
+}
+
+func TestDecodeDocument_current(t *testing.T) {
+	data, _ := encodeDocument(Tasks{{Id: 1, Description: "Go to store"}})
+	tasks, applied, err := decodeDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || len(applied) != 0 {
+		t.Error("Current document must be decoded without migrations!")
+	}
+}
+
+func TestDecodeDocument_newer(t *testing.T) {
+	if _, _, err := decodeDocument([]byte(`{"schema_version": 1000, "tasks": []}`)); err == nil {
+		t.Error("Document from a newer version must not be decoded!")
+	}
+}
+
+func TestDecodeDocument_migrations(t *testing.T) {
+	defer func(m []migration) {
+		migrations = m
+		schemaVersion = len(m)
+	}(migrations)
+	migrations = append(migrations, migration{
+		description: "upper case the description",
+		migrate: func(doc map[string]interface{}) error {
+			for _, raw := range doc["tasks"].([]interface{}) {
+				task := raw.(map[string]interface{})
+				task["description"] = strings.ToUpper(task["description"].(string))
+			}
+			return nil
+		},
+	})
+	current := schemaVersion
+	schemaVersion = len(migrations)
+	tasks, applied, err := decodeDocument([]byte(fmt.Sprintf(`{"schema_version": %d, "tasks": [{"id":1,"description":"Go to store"}]}`, current)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || tasks[0].Description != "GO TO STORE" {
+		t.Error("Failed to run the pending migration!")
+	}
+}
+
+func TestDecodeDocument_legacyTimes(t *testing.T) {
+	tasks, _, err := decodeDocument([]byte(`[
+		{"id":1,"description":"Go to store","created":"Fri, 07/21/17, 12:13PM","updated":"","remind_at":"2017-07-22 10:30","completed":""},
+		{"id":2,"description":"Learn golang testing","created":"Fri, 07/21/17, 12:13PM","updated":"","remind_at":"tomorrow","completed":"Fri, 07/21/17, 24:10PM"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tasks[0].Created.Equal(time.Date(2017, 7, 21, 12, 13, 0, 0, time.Local)) {
+		t.Error("Failed to migrate created time!", tasks[0].Created)
+	}
+	if tasks[0].RemindAt == nil || !tasks[0].RemindAt.Equal(time.Date(2017, 7, 22, 10, 30, 0, 0, time.Local)) {
+		t.Error("Failed to migrate reminder time!")
+	}
+	if tasks[0].Updated != nil || tasks[0].Completed != nil {
+		t.Error("Empty times must be migrated to null!")
+	}
+	if tasks[1].Completed == nil {
+		t.Error("Completed task must stay completed even with an invalid time!")
+	}
+	if tasks[1].RemindAt != nil {
+		t.Error("Invalid reminder time must be dropped!", tasks[1].RemindAt)
+	}
+	_, _, err = decodeDocument([]byte(`[{"id":1,"description":"Go to store","created":"yesterday"}]`))
+	if !errors.Is(err, ErrCorruptDB) {
+		t.Error("Invalid created time must return ErrCorruptDB", err)
+	}
+}
+
+func TestDecodeDocument_tags(t *testing.T) {
+	tasks, applied, err := decodeDocument([]byte(`{"schema_version": 2, "tasks": [
+		{"id":1,"description":"Go to store","tag":"Low"},
+		{"id":2,"description":"Learn golang testing","tag":""}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || len(tasks[0].Tags) != 1 || tasks[0].Tags[0] != "low" || tasks[1].Tags != nil {
+		t.Error("Failed to migrate the tag into the tags list!", tasks)
+	}
+}
+
+func TestFileStorage_Upgrade(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fs := NewFileStorage(filepath.Join(dir, "tasks.json"))
+	legacy := []byte(`[{"id":1,"uid":"a","description":"Go to store"}]`)
+	ioutil.WriteFile(fs.Path, legacy, 0644)
+	applied, err := fs.Upgrade(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(fs.Path); len(applied) != schemaVersion || string(data) != string(legacy) {
+		t.Error("Dry run must report the migrations without changing the file!")
+	}
+	if _, err := fs.Upgrade(false); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(fs.Path); !strings.HasPrefix(string(data), `{"schema_version":`) {
+		t.Error("File was not upgraded to a versioned document!")
+	}
+	if applied, _ := fs.Upgrade(true); len(applied) != 0 {
+		t.Error("Upgraded file must not need any migration!")
+	}
+}
@@ -0,0 +1,406 @@
+package taskmanager
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// pure go sqlite driver, no cgo required
+	_ "modernc.org/sqlite"
+)
+
+// sqliteFileName is the default sqlite database file name
+const sqliteFileName = ".task.sqlite"
+
+// sqliteMigration is a schema change of the sqlite database
+type sqliteMigration struct {
+	description string
+	migrate     func(tx *sql.Tx) error
+}
+
+// sqliteMigrations are applied in order, PRAGMA user_version keeps
+// track of how many of them have already been applied to a database
+var sqliteMigrations = []sqliteMigration{
+	sqlStatement("create the tasks table", `CREATE TABLE IF NOT EXISTS tasks (
+		uid         TEXT PRIMARY KEY,
+		id          INTEGER NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		tag         TEXT NOT NULL DEFAULT '',
+		created     TEXT NOT NULL DEFAULT '',
+		updated     TEXT NOT NULL DEFAULT '',
+		remind_at   TEXT NOT NULL DEFAULT '',
+		completed   TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS tasks_id ON tasks (id);
+	CREATE INDEX IF NOT EXISTS tasks_completed ON tasks (completed);
+	CREATE INDEX IF NOT EXISTS tasks_reminder ON tasks (completed, remind_at);`),
+	// times used to be stored in the legacy layouts, see schema version 2
+	{description: "store created, updated, remind_at and completed as RFC 3339 times", migrate: func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT uid, created, updated, remind_at, completed FROM tasks")
+		if err != nil {
+			return err
+		}
+		var updates [][]string
+		for rows.Next() {
+			var uid, created, updated, remindAt, completed string
+			if err := rows.Scan(&uid, &created, &updated, &remindAt, &completed); err != nil {
+				rows.Close()
+				return err
+			}
+			task := map[string]interface{}{"id": uid, "created": created, "updated": updated, "remind_at": remindAt, "completed": completed}
+			if err := legacyTimes(task); err != nil {
+				rows.Close()
+				return err
+			}
+			updates = append(updates, []string{
+				sqlText(task["created"]),
+				sqlText(task["updated"]),
+				sqlText(task["remind_at"]),
+				sqlText(task["completed"]),
+				uid,
+			})
+		}
+		rows.Close()
+		for _, u := range updates {
+			_, err := tx.Exec("UPDATE tasks SET created = ?, updated = ?, remind_at = ?, completed = ? WHERE uid = ?", u[0], u[1], u[2], u[3], u[4])
+			if err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}},
+	sqlStatement("add the notified_at column", `ALTER TABLE tasks ADD COLUMN notified_at TEXT NOT NULL DEFAULT ''`),
+	sqlStatement("add the repeat_every column", `ALTER TABLE tasks ADD COLUMN repeat_every INTEGER NOT NULL DEFAULT 0`),
+	sqlStatement("add the reminders column", `ALTER TABLE tasks ADD COLUMN reminders TEXT NOT NULL DEFAULT ''`),
+	sqlStatement("add the due column", `ALTER TABLE tasks ADD COLUMN due TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS tasks_due ON tasks (completed, due);`),
+	sqlStatement("add the recurrence column", `ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT ''`),
+	// the single tag moves into a json list, the old column stays empty
+	sqlStatement("move the single tag into the tags list", `ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+	UPDATE tasks SET tags = json_array(lower(trim(tag))), tag = '' WHERE trim(tag) != '';`),
+	sqlStatement("add the priority column", `ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`),
+	sqlStatement("add the project column", `ALTER TABLE tasks ADD COLUMN project TEXT NOT NULL DEFAULT ''`),
+	sqlStatement("add the parent column", `ALTER TABLE tasks ADD COLUMN parent TEXT NOT NULL DEFAULT ''`),
+	sqlStatement("add the depends_on column", `ALTER TABLE tasks ADD COLUMN depends_on TEXT NOT NULL DEFAULT ''`),
+}
+
+// sqliteColumns is the column list in the order query scans them
+const sqliteColumns = "id, uid, description, tags, created, updated, remind_at, notified_at, completed, repeat_every, reminders, due, recurrence, priority, project, parent, depends_on"
+
+// SQLiteStorage keeps the tasks in a sqlite database, pending, completed
+// and reminder tasks are served by indexed queries
+type SQLiteStorage struct {
+	Path string
+	db   *sql.DB
+}
+
+// Querier is implemented by storages that can filter tasks on their own,
+// the Get*Tasks methods of a List use it instead of scanning the whole list,
+// a plain Tasks value is always scanned
+type Querier interface {
+	PendingTasks() (Tasks, error)
+	CompletedTasks() (Tasks, error)
+	ReminderTasks() (Tasks, error)
+}
+
+// NewSQLiteStorage return a sqlite storage for the given path
+func NewSQLiteStorage(path string) *SQLiteStorage {
+	return &SQLiteStorage{Path: path}
+}
+
+// Load read all the tasks
+func (ss *SQLiteStorage) Load() (Tasks, error) {
+	return ss.query("")
+}
+
+// Save write the task list in a single transaction
+func (ss *SQLiteStorage) Save(tasks Tasks) error {
+	db, err := ss.open()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("CREATE TEMP TABLE IF NOT EXISTS keep (uid TEXT PRIMARY KEY); DELETE FROM keep"); err != nil {
+		return err
+	}
+	upsert, err := tx.Prepare("INSERT OR REPLACE INTO tasks (" + sqliteColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+	for _, task := range tasks {
+		key := recordKey(task)
+		reminders, err := sqlReminders(task.Reminders)
+		if err != nil {
+			return err
+		}
+		tags, err := sqlTags(task.Tags)
+		if err != nil {
+			return err
+		}
+		dependsOn, err := sqlTags(task.DependsOn)
+		if err != nil {
+			return err
+		}
+		if _, err := upsert.Exec(task.Id, key, task.Description, tags, sqlTime(&task.Created), sqlTime(task.Updated), sqlTime(task.RemindAt), sqlTime(task.NotifiedAt), sqlTime(task.Completed), task.RepeatEvery, reminders, sqlTime(task.Due), task.Recurrence, task.Priority, task.Project, task.Parent, dependsOn); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO keep (uid) VALUES (?)", key); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM tasks WHERE uid NOT IN (SELECT uid FROM keep)"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Lock take an advisory lock next to the database file
+func (ss *SQLiteStorage) Lock() (func(), error) {
+	return lockFile(lockFilePath(ss.Path))
+}
+
+// Watch notify whenever the database file is modified
+func (ss *SQLiteStorage) Watch(done <-chan struct{}) (<-chan struct{}, error) {
+	if _, err := ss.open(); err != nil {
+		return nil, err
+	}
+	return watchFile(ss.Path, done)
+}
+
+// PendingTasks query all the pending tasks
+func (ss *SQLiteStorage) PendingTasks() (Tasks, error) {
+	return ss.query("WHERE completed = ''")
+}
+
+// CompletedTasks query all the completed tasks
+func (ss *SQLiteStorage) CompletedTasks() (Tasks, error) {
+	return ss.query("WHERE completed != ''")
+}
+
+// ReminderTasks query all the pending tasks that have a reminder
+func (ss *SQLiteStorage) ReminderTasks() (Tasks, error) {
+	return ss.query("WHERE completed = '' AND (remind_at != '' OR reminders != '')")
+}
+
+// Close close the underlying database
+func (ss *SQLiteStorage) Close() error {
+	if ss.db == nil {
+		return nil
+	}
+	err := ss.db.Close()
+	ss.db = nil
+	return err
+}
+
+// run a select on the tasks table with the given where clause
+func (ss *SQLiteStorage) query(where string) (Tasks, error) {
+	db, err := ss.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT " + sqliteColumns + " FROM tasks " + where + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tasks Tasks
+	for rows.Next() {
+		var task Task
+		var tags, created, updated, remindAt, notifiedAt, completed, reminders, due, dependsOn string
+		if err := rows.Scan(&task.Id, &task.UID, &task.Description, &tags, &created, &updated, &remindAt, &notifiedAt, &completed, &task.RepeatEvery, &reminders, &due, &task.Recurrence, &task.Priority, &task.Project, &task.Parent, &dependsOn); err != nil {
+			return nil, err
+		}
+		var c *time.Time
+		if c, err = parseSQLTime(created); err == nil && c != nil {
+			task.Created = *c
+		}
+		if err == nil {
+			task.Tags, err = parseSQLTags(tags)
+		}
+		if err == nil {
+			task.Updated, err = parseSQLTime(updated)
+		}
+		if err == nil {
+			task.RemindAt, err = parseSQLTime(remindAt)
+		}
+		if err == nil {
+			task.NotifiedAt, err = parseSQLTime(notifiedAt)
+		}
+		if err == nil {
+			task.Completed, err = parseSQLTime(completed)
+		}
+		if err == nil {
+			task.Reminders, err = parseSQLReminders(reminders)
+		}
+		if err == nil {
+			task.Due, err = parseSQLTime(due)
+		}
+		if err == nil {
+			task.DependsOn, err = parseSQLTags(dependsOn)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(task.UID, "id-") {
+			task.UID = ""
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// open the database once and bring its schema up to date
+func (ss *SQLiteStorage) open() (*sql.DB, error) {
+	if ss.db != nil {
+		return ss.db, nil
+	}
+	if _, err := ss.upgrade(false); err != nil {
+		return nil, err
+	}
+	return ss.db, nil
+}
+
+// Upgrade bring the database to the current schema version, an open
+// database already is
+func (ss *SQLiteStorage) Upgrade(dryRun bool) ([]string, error) {
+	if ss.db != nil {
+		return nil, nil
+	}
+	return ss.upgrade(dryRun)
+}
+
+// connect to the database and run the migrations it needs, it is kept open
+// unless dryRun only reports them
+func (ss *SQLiteStorage) upgrade(dryRun bool) ([]string, error) {
+	db, err := sql.Open("sqlite", ss.Path)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection, so the pragma applies to every statement
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, err
+	}
+	var applied []string
+	for i := version; i < len(sqliteMigrations); i++ {
+		if !dryRun {
+			if err := migrateSQLite(db, i); err != nil {
+				db.Close()
+				return applied, fmt.Errorf("migration to schema version %d failed: %w", i+1, err)
+			}
+		}
+		applied = append(applied, fmt.Sprintf("v%d -> v%d: %s", i, i+1, sqliteMigrations[i].description))
+	}
+	if dryRun {
+		return applied, db.Close()
+	}
+	ss.db = db
+	return applied, nil
+}
+
+// apply a single migration and bump the version in one transaction
+func migrateSQLite(db *sql.DB, i int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := sqliteMigrations[i].migrate(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("PRAGMA user_version = " + strconv.Itoa(i+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// a migration that only runs the given statements
+func sqlStatement(description, stmt string) sqliteMigration {
+	return sqliteMigration{description: description, migrate: func(tx *sql.Tx) error {
+		_, err := tx.Exec(stmt)
+		return err
+	}}
+}
+
+// format an optional time for a TEXT column, null is stored as an empty string
+func sqlTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parse a TEXT column written by sqlTime
+func parseSQLTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptDB, err)
+	}
+	return &t, nil
+}
+
+// encode the extra reminders as json for a TEXT column, none is stored as an empty string
+func sqlReminders(reminders []Reminder) (string, error) {
+	if len(reminders) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(reminders)
+	return string(data), err
+}
+
+// parse a TEXT column written by sqlReminders
+func parseSQLReminders(value string) ([]Reminder, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var reminders []Reminder
+	if err := json.Unmarshal([]byte(value), &reminders); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptDB, err)
+	}
+	return reminders, nil
+}
+
+// encode the tags or the dependencies as a json list for a TEXT column, none
+// is stored as an empty string
+func sqlTags(tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(tags)
+	return string(data), err
+}
+
+// parse a TEXT column written by sqlTags
+func parseSQLTags(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(value), &tags); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptDB, err)
+	}
+	return tags, nil
+}
+
+// the TEXT column value of a converted legacy time, null is stored empty
+func sqlText(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
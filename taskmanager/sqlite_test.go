@@ -0,0 +1,164 @@
+package taskmanager
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStorage_SaveLoad(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ss := NewSQLiteStorage(filepath.Join(dir, "tasks.sqlite"))
+	defer ss.Close()
+	tasks := Tasks{
+		{Id: 1, UID: "a", Description: "Go to store", Tags: []string{"low", "errand"}},
+		{Id: 2, UID: "b", Description: "Learn golang testing", Completed: timeAt("2017-07-21T12:13:00Z"), Due: timeAt("2017-07-21T17:00:00Z"), Recurrence: "FREQ=WEEKLY"},
+		{Id: 3, UID: "c", Description: "Meeting with John", RemindAt: timeAt("2017-07-22T10:30:00Z"), RepeatEvery: 5, Reminders: []Reminder{{Before: 60}}, Priority: PriorityHigh, Project: "work.meetings", Parent: "a", DependsOn: []string{"a", "b"}},
+	}
+	if err := ss.Save(tasks); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.Save(tasks[1:]); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ss.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || !reflect.DeepEqual(loaded, tasks[1:]) {
+		t.Error("Failed to load saved tasks from sqlite!", loaded)
+	}
+}
+
+func TestSQLiteStorage_queries(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ss := NewSQLiteStorage(filepath.Join(dir, "tasks.sqlite"))
+	defer ss.Close()
+	ss.Save(Tasks{
+		{Id: 1, UID: "a", Description: "Go to store"},
+		{Id: 2, UID: "b", Description: "Learn golang testing", Completed: timeAt("2017-07-21T12:13:00Z")},
+		{Id: 3, UID: "c", Description: "Meeting with John", RemindAt: timeAt("2017-07-22T10:30:00Z")},
+		{Id: 4, UID: "d", Description: "Call mom", Reminders: []Reminder{{At: timeAt("2017-07-22T18:00:00Z")}}},
+	})
+	tasks, err := New(ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := tasks.GetPendingTasks(); len(pending) != 3 || pending[0].Id != 4 {
+		t.Error("Failed to query pending tasks!")
+	}
+	if completed := tasks.GetCompletedTasks(); len(completed) != 1 || completed[0].Id != 2 {
+		t.Error("Failed to query completed tasks!")
+	}
+	if reminders := tasks.GetReminderTasks(); len(reminders) != 2 || reminders[0].Id != 4 {
+		t.Error("Failed to query reminder tasks!")
+	}
+	// a part of the list is not answered from the index
+	if pending := tasks.Tasks[:2].GetPendingTasks(); len(pending) != 1 || pending[0].Id != 1 {
+		t.Error("Subset of the list must be filtered on its own!", pending)
+	}
+	if reminders := (Tasks{}).GetReminderTasks(); len(reminders) != 0 {
+		t.Error("Empty list must not have reminders!", reminders)
+	}
+}
+
+func TestSQLiteStorage_legacyTimes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ss := NewSQLiteStorage(filepath.Join(dir, "tasks.sqlite"))
+	// create a database of schema version 1 with the legacy layout
+	db, err := sql.Open("sqlite", ss.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateSQLite(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("INSERT INTO tasks (uid, id, description, created, remind_at) VALUES ('a', 1, 'Go to store', 'Fri, 07/21/17, 12:13PM', '2017-07-22 10:30')")
+	db.Close()
+	tasks, err := ss.Load()
+	defer ss.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tasks[0].Created.Equal(time.Date(2017, 7, 21, 12, 13, 0, 0, time.Local)) || tasks[0].RemindAt == nil {
+		t.Error("Failed to migrate legacy times in sqlite!")
+	}
+}
+
+func TestSQLiteStorage_Upgrade(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tasks.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateSQLite(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	var upgrader Upgrader = NewSQLiteStorage(path)
+	applied, err := upgrader.Upgrade(true)
+	if err != nil || len(applied) != len(sqliteMigrations)-1 || !strings.HasPrefix(applied[0], "v1 -> v2: ") {
+		t.Fatal("Dry run must report the pending migrations", applied, err)
+	}
+	ss := NewSQLiteStorage(path)
+	defer ss.Close()
+	if applied, err := ss.Upgrade(false); err != nil || len(applied) != len(sqliteMigrations)-1 {
+		t.Fatal("Failed to upgrade the database", applied, err)
+	}
+	if applied, err := NewSQLiteStorage(path).Upgrade(true); err != nil || len(applied) != 0 {
+		t.Error("Upgraded database must be up to date", applied, err)
+	}
+}
+
+func TestSQLiteStorage_tags(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ss := NewSQLiteStorage(filepath.Join(dir, "tasks.sqlite"))
+	// a database from before the tags list had a single tag column
+	db, err := sql.Open("sqlite", ss.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// migration 7 adds the tags list
+	for i := 0; i < 7; i++ {
+		if err := migrateSQLite(db, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Exec("INSERT INTO tasks (uid, id, description, tag) VALUES ('a', 1, 'Go to store', 'Low'), ('b', 2, 'Learn golang testing', '')")
+	db.Close()
+	tasks, err := ss.Load()
+	defer ss.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || !reflect.DeepEqual(tasks[0].Tags, []string{"low"}) || tasks[1].Tags != nil {
+		t.Error("Failed to migrate the tag into the tags list in sqlite!", tasks)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	from := NewFileStorage(filepath.Join(dir, "tasks.json"))
+	from.Save(Tasks{{Id: 1, UID: "a", Description: "Go to store"}, {Id: 2, Description: "Learn golang testing"}})
+	to := NewSQLiteStorage(filepath.Join(dir, "tasks.sqlite"))
+	defer to.Close()
+	n, err := Migrate(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, _ := to.Load()
+	if n != 2 || len(tasks) != 2 || tasks[1].UID != "" {
+		t.Error("Failed to migrate tasks to sqlite!")
+	}
+}
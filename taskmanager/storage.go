@@ -0,0 +1,330 @@
+package taskmanager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Storage describes a backend where the task list is persisted
+type Storage interface {
+	// Load read the whole task list from the storage
+	Load() (Tasks, error)
+	// Save persist the whole task list to the storage
+	Save(tasks Tasks) error
+	// Watch send a signal to the returned channel whenever the storage is
+	// changed by someone else, it stops watching when done is closed
+	Watch(done <-chan struct{}) (<-chan struct{}, error)
+}
+
+// Locker is implemented by storages that can be locked across processes,
+// the task list holds the lock during every read-modify-write cycle
+type Locker interface {
+	// Lock block until the storage is locked, the returned func releases it
+	Lock() (unlock func(), err error)
+}
+
+// FileStorage is the default storage, it keeps the task list in a json file
+type FileStorage struct {
+	Path string
+}
+
+// NewFileStorage return a json file storage for the given path
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{Path: path}
+}
+
+// DefaultStorage return the storage configured by the environment,
+// TASK_DB_DRIVER selects the backend and TASK_DB_FILE_PATH its location
+func DefaultStorage() (Storage, error) {
+	return NewStorage(os.Getenv("TASK_DB_DRIVER"))
+}
+
+// NewStorage return the storage of a driver (json, bolt or sqlite) at its default location
+func NewStorage(driver string) (Storage, error) {
+	var name string
+	switch strings.ToLower(driver) {
+	case "", "json":
+		name = dbFileName
+	case "bolt", "bbolt":
+		name = boltFileName
+	case "sqlite":
+		name = sqliteFileName
+	default:
+		return nil, fmt.Errorf("%w: unknown storage driver %q", ErrStorage, driver)
+	}
+	path, err := dbFilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case boltFileName:
+		return NewBoltStorage(path), nil
+	case sqliteFileName:
+		return NewSQLiteStorage(path), nil
+	}
+	return NewFileStorage(path), nil
+}
+
+// Migrate copy all the tasks from one storage to another, it returns the number of copied tasks
+func Migrate(from, to Storage) (int, error) {
+	tasks, err := from.Load()
+	if err != nil {
+		return 0, err
+	}
+	if err := to.Save(tasks); err != nil {
+		return 0, err
+	}
+	return len(tasks), nil
+}
+
+// Load read the task list from the json file
+func (fs *FileStorage) Load() (Tasks, error) {
+	if _, err := os.Stat(backupFile(fs.Path)); os.IsNotExist(err) {
+		createDBFileIfNotExist(fs.Path)
+	}
+	return readDBFile(fs.Path)
+}
+
+// Save write the task list to the json file
+func (fs *FileStorage) Save(tasks Tasks) error {
+	return writeDBFile(fs.Path, tasks)
+}
+
+// Lock take an advisory lock on the json file
+func (fs *FileStorage) Lock() (func(), error) {
+	return lockFile(lockFilePath(fs.Path))
+}
+
+// Upgrade rewrite the json file in the current schema version
+func (fs *FileStorage) Upgrade(dryRun bool) ([]string, error) {
+	unlock, err := fs.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	data, err := ioutil.ReadFile(fs.Path)
+	if err != nil {
+		return nil, err
+	}
+	tasks, applied, err := decodeDocument(data)
+	if err != nil || dryRun || len(applied) == 0 {
+		return applied, err
+	}
+	return applied, writeDBFile(fs.Path, tasks)
+}
+
+// Watch notify whenever the json file is modified
+func (fs *FileStorage) Watch(done <-chan struct{}) (<-chan struct{}, error) {
+	return watchFile(fs.Path, done)
+}
+
+// watch the directory of a file and notify when the file is written,
+// created or replaced. The directory is watched since an atomic save
+// renames a new file over the old one.
+func watchFile(path string, done <-chan struct{}) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	name := filepath.Clean(path)
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != name || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) {
+					continue
+				}
+				// a save fires several events, collapse them into one signal
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// record key of a task, tasks without uid fall back to their id
+func recordKey(task Task) string {
+	if task.UID != "" {
+		return task.UID
+	}
+	return "id-" + strconv.Itoa(task.Id)
+}
+
+// get file path of a database, name is used when TASK_DB_FILE_PATH is a directory.
+// When it is the file of another driver the database is put next to it.
+func dbFilePath(name string) (string, error) {
+	env := os.Getenv("TASK_DB_FILE_PATH")
+	if env != "" {
+		if strings.HasSuffix(env, filepath.Ext(name)) {
+			return env, nil
+		}
+		if fi, err := os.Stat(env); err == nil && fi.IsDir() || !isDBFile(env) {
+			return filepath.Join(filepath.Clean(env), name), nil
+		}
+		return filepath.Join(filepath.Dir(env), name), nil
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("%w: can not find the home directory: %v", ErrStorage, err)
+	}
+	return filepath.Join(usr.HomeDir, name), nil
+}
+
+// tell whether a path names the file of one of the drivers
+func isDBFile(path string) bool {
+	for _, name := range []string{dbFileName, boltFileName, sqliteFileName} {
+		if filepath.Ext(path) == filepath.Ext(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// load database, a corrupt or missing file is recovered from its backup
+func readDBFile(path string) (Tasks, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	tasks, e := parseDBFile(path)
+	if e == nil {
+		return tasks, nil
+	}
+	if backup, err := parseDBFile(backupFile(path)); err == nil {
+		return backup, nil
+	}
+	return nil, e
+}
+
+// parse a json database file of any schema version, an empty file is an empty task list
+func parseDBFile(path string) (Tasks, error) {
+	//load the json to task
+	file, e := ioutil.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+	tasks, _, e := decodeDocument(file)
+	if e != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptDB, path, e)
+	}
+	return tasks, nil
+}
+
+// write to json atomically: the data goes to a temp file which is
+// fsynced and renamed over the database, the previous version is kept as backup
+func writeDBFile(path string, tasks Tasks) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	taskJson, e := encodeDocument(tasks)
+	if e != nil {
+		return e
+	}
+	tmp, e := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if e != nil {
+		return e
+	}
+	defer os.Remove(tmp.Name())
+	if _, e := tmp.Write(taskJson); e != nil {
+		tmp.Close()
+		return e
+	}
+	if e := tmp.Sync(); e != nil {
+		tmp.Close()
+		return e
+	}
+	if e := tmp.Close(); e != nil {
+		return e
+	}
+	if e := os.Chmod(tmp.Name(), 0644); e != nil {
+		return e
+	}
+	if e := backupDBFile(path); e != nil {
+		return e
+	}
+	if e := os.Rename(tmp.Name(), path); e != nil {
+		return e
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// keep the current database as backup, a hard link is used when
+// possible so the database itself is never missing
+func backupDBFile(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	// never replace a good backup with a corrupt database
+	if _, err := parseDBFile(path); err != nil {
+		return nil
+	}
+	backup := backupFile(path)
+	os.Remove(backup)
+	if err := os.Link(path, backup); err == nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(backup, data, 0644)
+}
+
+// flush a directory so a rename inside it is durable, not supported everywhere
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// lock file path of a database
+func lockFilePath(path string) string {
+	return path + ".lock"
+}
+
+// backup file path of a database
+func backupFile(path string) string {
+	return path + ".bak"
+}
+
+// create a db file if not exist
+func createDBFileIfNotExist(path string) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		os.Create(path)
+	}
+}
+
+// delete a db file if exist
+func removeDBFileIfExist(path string) {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,184 @@
+package taskmanager
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// memoryStorage is an in memory Storage used to test pluggable backends
+type memoryStorage struct {
+	tasks   Tasks
+	saves   int
+	err     error
+	changes chan struct{}
+}
+
+func (ms *memoryStorage) Load() (Tasks, error) {
+	return append(Tasks{}, ms.tasks...), nil
+}
+
+func (ms *memoryStorage) Save(tasks Tasks) error {
+	if ms.err != nil {
+		return ms.err
+	}
+	ms.tasks = append(Tasks{}, tasks...)
+	ms.saves++
+	return nil
+}
+
+func (ms *memoryStorage) Watch(done <-chan struct{}) (<-chan struct{}, error) {
+	if ms.changes != nil {
+		return ms.changes, nil
+	}
+	return make(chan struct{}), nil
+}
+
+func timeAt(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFileStorage_SaveLoad(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fs := NewFileStorage(filepath.Join(dir, "tasks.json"))
+	tasks, err := fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Error("New file storage must be empty!")
+	}
+	if err := fs.Save(Tasks{{Id: 1, Description: "Go to store"}}); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err = fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Description != "Go to store" {
+		t.Error("Failed to load saved tasks!")
+	}
+}
+
+func TestFileStorage_backup(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fs := NewFileStorage(filepath.Join(dir, "tasks.json"))
+	fs.Save(Tasks{{Id: 1, Description: "Go to store"}})
+	fs.Save(Tasks{{Id: 1, Description: "Go to store"}, {Id: 2, Description: "Learn golang testing"}})
+	backup, err := parseDBFile(backupFile(fs.Path))
+	if err != nil || len(backup) != 1 {
+		t.Error("Previous version was not kept as backup!")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Error("Temp file was left behind!")
+	}
+	// corrupt the database, the backup must be used instead
+	ioutil.WriteFile(fs.Path, []byte(`[{"id": 1, "desc`), 0644)
+	tasks, err := fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Error("Failed to fall back to the backup!")
+	}
+	// a corrupt database must not overwrite the good backup
+	fs.Save(tasks)
+	if backup, _ := parseDBFile(backupFile(fs.Path)); len(backup) != 1 {
+		t.Error("Backup was replaced by a corrupt database!")
+	}
+	os.Remove(backupFile(fs.Path))
+	ioutil.WriteFile(fs.Path, []byte(`[{"id": 1, "desc`), 0644)
+	if _, err := fs.Load(); err == nil {
+		t.Error("Corrupt database without backup must fail to load!")
+	}
+}
+
+func TestFileStorage_Watch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fs := NewFileStorage(filepath.Join(dir, "tasks.json"))
+	fs.Load()
+	done := make(chan struct{})
+	defer close(done)
+	changes, err := fs.Watch(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.Save(Tasks{{Id: 1, Description: "Go to store"}})
+	select {
+	case <-changes:
+	case <-time.After(3 * time.Second):
+		t.Error("Storage change was not notified!")
+	}
+}
+
+func TestNew_customStorage(t *testing.T) {
+	ms := &memoryStorage{tasks: Tasks{{Id: 1, Description: "Go to store"}}}
+	tasks, err := New(ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks.TotalTask() != 1 {
+		t.Error("Failed to load tasks from custom storage!")
+	}
+	tasks.Add("Learn golang testing", nil, nil)
+	if ms.saves != 1 || len(ms.tasks) != 2 {
+		t.Error("Task was not saved to custom storage!")
+	}
+}
+
+func TestNew_separateStorages(t *testing.T) {
+	first, second := &memoryStorage{}, &memoryStorage{}
+	work, _ := New(first)
+	home, _ := New(second)
+	work.Add("Fix login", nil, nil)
+	home.Add("Go to store", nil, nil)
+	if len(first.tasks) != 1 || first.tasks[0].Description != "Fix login" || len(second.tasks) != 1 {
+		t.Error("Each list must save to the storage it was loaded from!", first.tasks, second.tasks)
+	}
+}
+
+func TestMigrate_saveFails(t *testing.T) {
+	from := &memoryStorage{tasks: Tasks{{Id: 1, Description: "Go to store"}}}
+	to := &memoryStorage{err: errors.New("disk full")}
+	if n, err := Migrate(from, to); err == nil || n != 0 {
+		t.Error("Failed migration must copy no tasks", n, err)
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	fs := NewFileStorage(filepath.Join(dir, "tasks.json"))
+	ioutil.WriteFile(fs.Path, []byte(`[{"id": 1, "desc`), 0644)
+	if _, err := New(fs); !errors.Is(err, ErrCorruptDB) {
+		t.Error("Corrupt database must return ErrCorruptDB", err)
+	}
+	ms := &memoryStorage{tasks: Tasks{{Id: 1, Description: "Go to store"}}}
+	tasks, _ := New(ms)
+	ms.err = errors.New("disk full")
+	if _, err := tasks.Add("Learn golang testing", nil, nil); !errors.Is(err, ErrStorage) {
+		t.Error("Failed save must return ErrStorage", err)
+	}
+	if _, err := tasks.UpdateTask(1, "Go to market"); err == nil || len(tasks.Tasks) != 1 || tasks.Tasks[0].Description != "Go to store" {
+		t.Error("Failed save must leave the list as it was", tasks.Tasks)
+	}
+}
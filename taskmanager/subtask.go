@@ -0,0 +1,228 @@
+package taskmanager
+
+import "fmt"
+
+// AutoCompleteParents completes a parent task when its last open subtask
+// is completed
+var AutoCompleteParents = false
+
+// Node is a task in the order of Tree, Depth is 0 for a top level task
+type Node struct {
+	Task  Task
+	Depth int
+}
+
+// Subtasks fetch the direct subtasks of a task, in their order
+func (t Tasks) Subtasks(task Task) Tasks {
+	var subtasks Tasks
+	if task.UID == "" {
+		return subtasks
+	}
+	for _, item := range t {
+		if item.Parent == task.UID {
+			subtasks = append(subtasks, item)
+		}
+	}
+	return subtasks
+}
+
+// ParentOf fetch the parent of a task, false for a top level task
+func (t Tasks) ParentOf(task Task) (Task, bool) {
+	if task.Parent == "" {
+		return Task{}, false
+	}
+	for _, item := range t {
+		if item.UID == task.Parent {
+			return item, true
+		}
+	}
+	return Task{}, false
+}
+
+// Tree order the tasks so the subtasks follow their parent, each level in
+// the order of the list. A task whose parent is not in the list is shown at
+// the top level.
+func (t Tasks) Tree() []Node {
+	listed := map[string]bool{}
+	for _, item := range t {
+		if item.UID != "" {
+			listed[item.UID] = true
+		}
+	}
+	var nodes []Node
+	seen := make([]bool, len(t))
+	var walk func(i, depth int)
+	walk = func(i, depth int) {
+		seen[i] = true
+		nodes = append(nodes, Node{Task: t[i], Depth: depth})
+		if t[i].UID == "" {
+			return
+		}
+		for j, item := range t {
+			if !seen[j] && item.Parent == t[i].UID {
+				walk(j, depth+1)
+			}
+		}
+	}
+	for i, item := range t {
+		if !seen[i] && !listed[item.Parent] {
+			walk(i, 0)
+		}
+	}
+	// a hand edited database may hold a loop without a top level task
+	for i := range t {
+		if !seen[i] {
+			walk(i, 0)
+		}
+	}
+	return nodes
+}
+
+// SetParent make a task a subtask of another by id, a zero parent id makes
+// it a top level task again
+func (l *List) SetParent(id, parentId int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	parent := ""
+	if parentId != 0 {
+		if err := l.isValidId(parentId); err != nil {
+			return Task{}, err
+		}
+		p, err := l.getIndexIdNo(parentId)
+		if err != nil {
+			return Task{}, err
+		}
+		if p == i || l.isDescendant(p, i) {
+			return Task{}, fmt.Errorf("%w: task %d is inside task %d", ErrInvalidParent, parentId, id)
+		}
+		parent = l.ensureUID(p)
+	}
+	l.Tasks[i].Parent = parent
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+// ForceCompleteTask mark a task as completed by id even when it has pending
+// subtasks, they stay pending
+func (l *List) ForceCompleteTask(id int) (Task, error) {
+	return l.completeTask(id, true)
+}
+
+// RemoveTaskTree delete a task by id with all its subtasks, it return the
+// number of removed tasks
+func (l *List) RemoveTaskTree(id int) (int, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return 0, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return 0, err
+	}
+	var kept Tasks
+	for j, item := range l.Tasks {
+		if j != i && !l.isDescendant(j, i) {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(l.Tasks) - len(kept)
+	l.Tasks = kept
+	return removed, l.save()
+}
+
+// complete a task by id, the parents of the task are completed along when
+// AutoCompleteParents is set and it was their last open subtask
+func (l *List) completeTask(id int, force bool) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if l.Tasks[i].Completed == nil && !force {
+		if open := l.openSubtasks(i); open > 0 {
+			return Task{}, fmt.Errorf("%w: task %d has %d pending", ErrOpenSubtasks, id, open)
+		}
+	}
+	l.complete(i)
+	for p := l.parentIndex(i); AutoCompleteParents && p >= 0; p = l.parentIndex(p) {
+		if l.Tasks[p].Completed != nil || l.openSubtasks(p) > 0 {
+			break
+		}
+		l.complete(p)
+	}
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+// mark the task at index i as completed, a recurring task gets its next
+// occurrence added
+func (t *Tasks) complete(i int) {
+	pending := (*t)[i].Completed == nil
+	(*t)[i].Completed = timePtr(now())
+	if pending {
+		if next, ok := (*t)[i].nextInstance(t.GetNextId(), now()); ok {
+			*t = append(*t, next)
+		}
+	}
+}
+
+// count the pending subtasks of the task at index i, nested ones included
+func (t Tasks) openSubtasks(i int) int {
+	open := 0
+	for j, item := range t {
+		if item.Completed == nil && t.isDescendant(j, i) {
+			open++
+		}
+	}
+	return open
+}
+
+// index of the parent of the task at index i, -1 for a top level task
+func (t Tasks) parentIndex(i int) int {
+	if t[i].Parent == "" {
+		return -1
+	}
+	for j, item := range t {
+		if item.UID == t[i].Parent {
+			return j
+		}
+	}
+	return -1
+}
+
+// tell whether the task at index i is below the task at index ancestor,
+// a loop in a hand edited database ends the walk
+func (t Tasks) isDescendant(i, ancestor int) bool {
+	for steps, p := 0, t.parentIndex(i); p >= 0 && steps < len(t); steps, p = steps+1, t.parentIndex(p) {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
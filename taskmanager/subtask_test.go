@@ -0,0 +1,81 @@
+package taskmanager
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTasks_Tree(t *testing.T) {
+	tasks := Tasks{
+		{Id: 5, UID: "e", Parent: "a"},
+		{Id: 4, UID: "d", Parent: "b"},
+		{Id: 3, UID: "c"},
+		{Id: 2, UID: "b", Parent: "a"},
+		{Id: 1, UID: "a"},
+		{Id: 6, UID: "f", Parent: "gone"},
+	}
+	var ids, depths []int
+	for _, node := range tasks.Tree() {
+		ids = append(ids, node.Task.Id)
+		depths = append(depths, node.Depth)
+	}
+	if !reflect.DeepEqual(ids, []int{3, 1, 5, 2, 4, 6}) || !reflect.DeepEqual(depths, []int{0, 0, 1, 1, 2, 0}) {
+		t.Error("Unexpected tree order", ids, depths)
+	}
+	if subtasks := tasks.Subtasks(tasks[4]); len(subtasks) != 2 || subtasks[0].Id != 5 {
+		t.Error("Unexpected subtasks", subtasks)
+	}
+	if parent, ok := tasks.ParentOf(tasks[1]); !ok || parent.Id != 2 {
+		t.Error("Unexpected parent", parent)
+	}
+}
+
+func TestTasks_SetParent(t *testing.T) {
+	tasks, _ := New(&memoryStorage{tasks: Tasks{{Id: 1, Description: "Release"}, {Id: 2, UID: "b", Description: "Write tests"}, {Id: 3, UID: "c", Description: "Run them"}}})
+	task, err := tasks.SetParent(2, 1)
+	if err != nil || task.Parent == "" || task.Parent != tasks.Tasks[0].UID {
+		t.Fatal("Failed to set the parent!", task.Parent, err)
+	}
+	if _, err := tasks.SetParent(3, 2); err != nil {
+		t.Fatal("Failed to nest the subtask!", err)
+	}
+	for _, parent := range []int{1, 3} {
+		if _, err := tasks.SetParent(1, parent); !errors.Is(err, ErrInvalidParent) {
+			t.Error("A task can not be inside itself", parent, err)
+		}
+	}
+	if task, _ := tasks.SetParent(3, 0); task.Parent != "" {
+		t.Error("Failed to clear the parent!")
+	}
+}
+
+func TestTasks_completeSubtasks(t *testing.T) {
+	defer func(auto bool) { AutoCompleteParents = auto }(AutoCompleteParents)
+	tasks, _ := New(&memoryStorage{tasks: Tasks{{Id: 1, UID: "a"}, {Id: 2, UID: "b", Parent: "a"}, {Id: 3, UID: "c", Parent: "b"}}})
+	if _, err := tasks.MarkAsCompleteTask(1); !errors.Is(err, ErrOpenSubtasks) {
+		t.Error("A task with open subtasks must not be completed", err)
+	}
+	if task, err := tasks.ForceCompleteTask(2); err != nil || task.Completed == nil || tasks.Tasks[2].Completed != nil {
+		t.Error("Failed to force the completion!", err)
+	}
+	tasks.MarkAsPendingTask(2)
+
+	AutoCompleteParents = true
+	if _, err := tasks.MarkAsCompleteTask(3); err != nil {
+		t.Fatal("Failed to complete the subtask!", err)
+	}
+	if tasks.Tasks[0].Completed == nil || tasks.Tasks[1].Completed == nil {
+		t.Error("The parents of the last subtask must be completed")
+	}
+}
+
+func TestTasks_RemoveTaskTree(t *testing.T) {
+	tasks, _ := New(&memoryStorage{tasks: Tasks{{Id: 1, UID: "a"}, {Id: 2, UID: "b", Parent: "a"}, {Id: 3, UID: "c", Parent: "b"}, {Id: 4, UID: "d"}}})
+	if err := tasks.RemoveTask(2); err != nil || tasks.Tasks[1].Parent != "a" {
+		t.Error("The subtasks must move up to the parent", err)
+	}
+	if n, err := tasks.RemoveTaskTree(1); err != nil || n != 2 || len(tasks.Tasks) != 1 || tasks.Tasks[0].Id != 4 {
+		t.Error("Failed to remove the tree!", n, err, tasks)
+	}
+}
@@ -0,0 +1,125 @@
+package taskmanager
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagCount is how many tasks carry a tag
+type TagCount struct {
+	Tag     string
+	Pending int
+	Total   int
+}
+
+// HasTag tell whether a task carries the tag, a leading + is ignored
+func (task Task) HasTag(tag string) bool {
+	tag = normalizeTag(tag)
+	for _, t := range task.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTasksByTags fetch the tasks carrying every one of the tags
+func (t Tasks) GetTasksByTags(tags ...string) Tasks {
+	var tagged Tasks
+	for _, item := range t {
+		all := true
+		for _, tag := range tags {
+			if !item.HasTag(tag) {
+				all = false
+				break
+			}
+		}
+		if all {
+			tagged = append(tagged, item)
+		}
+	}
+	sort.Sort(tagged)
+	return tagged
+}
+
+// GetTags count the tasks of every tag, the most used first
+func (t Tasks) GetTags() []TagCount {
+	counts := map[string]*TagCount{}
+	for _, item := range t {
+		for _, tag := range item.Tags {
+			c, ok := counts[tag]
+			if !ok {
+				c = &TagCount{Tag: tag}
+				counts[tag] = c
+			}
+			c.Total++
+			if item.Completed == nil {
+				c.Pending++
+			}
+		}
+	}
+	var tags []TagCount
+	for _, c := range counts {
+		tags = append(tags, *c)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Total != tags[j].Total {
+			return tags[i].Total > tags[j].Total
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// TagTask add and remove tags of a task by id, removing a tag it does
+// not carry is not an error
+func (l *List) TagTask(id int, add, remove []string) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	var tags []string
+	for _, tag := range l.Tasks[i].Tags {
+		drop := false
+		for _, r := range remove {
+			drop = drop || normalizeTag(r) == tag
+		}
+		if !drop {
+			tags = append(tags, tag)
+		}
+	}
+	l.Tasks[i].Tags = normalizeTags(append(tags, add...))
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+// normalize a list of tags, without empty and duplicate ones, nil when empty
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// tags are stored in lower case without the leading +
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "+"))
+}
@@ -2,16 +2,9 @@ package taskmanager
 
 import (
 	"crypto/rand"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
-	"os/user"
-	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,45 +13,177 @@ import (
 type (
 	// Task describes a task object
 	Task struct {
-		Id          int    `json:"id"`
-		UID         string `json:"uid"`
-		Description string `json:"description"`
-		Tag         string `json:"tag"`
-		Created     string `json:"created"`
-		Updated     string `json:"updated"`
-		RemindAt    string `json:"remind_at"`
-		Completed   string `json:"completed"`
+		Id          int        `json:"id"`
+		UID         string     `json:"uid"`
+		Description string     `json:"description"`
+		Tags        []string   `json:"tags"`
+		Created     time.Time  `json:"created"`
+		Updated     *time.Time `json:"updated"`
+		RemindAt    *time.Time `json:"remind_at"`
+		NotifiedAt  *time.Time `json:"notified_at"`
+		Completed   *time.Time `json:"completed"`
+		// RepeatEvery is how many minutes a fired reminder waits before
+		// it fires again, until it is acknowledged. Zero fires once.
+		RepeatEvery int `json:"repeat_every"`
+		// Reminders are the extra reminders on top of RemindAt
+		Reminders []Reminder `json:"reminders"`
+		// Due is when the task has to be done, unlike RemindAt nothing fires
+		Due *time.Time `json:"due"`
+		// Recurrence is an iCalendar RRULE, completing the task creates
+		// its next occurrence as a new task
+		Recurrence string `json:"recurrence"`
+		// Priority orders the task list, see DefaultSort
+		Priority Priority `json:"priority"`
+		// Project is the list the task belongs to, dotted names like
+		// work.backend nest, empty when it is in none
+		Project string `json:"project"`
+		// Parent is the UID of the task this one is a subtask of, empty
+		// for a top level task
+		Parent string `json:"parent"`
+		// DependsOn are the UIDs of the tasks that have to be completed
+		// before this one can be worked on
+		DependsOn []string `json:"depends_on"`
+	}
+
+	// Reminder is an extra reminder of a task, either at an absolute time
+	// or a number of minutes before the task's Due, or RemindAt without Due
+	Reminder struct {
+		At         *time.Time `json:"at"`
+		Before     int        `json:"before"`
+		NotifiedAt *time.Time `json:"notified_at"`
+	}
+
+	// Alarm is a single reminder of a task resolved to its time, a task
+	// has an alarm for its RemindAt and one for each of its Reminders
+	Alarm struct {
+		Task Task
+		// Index is 0 for the task's RemindAt and n for Reminders[n-1]
+		Index      int
+		At         time.Time
+		NotifiedAt *time.Time
+	}
+
+	// AddOptions are the settings of a new task besides its description,
+	// the zero value adds a plain task
+	AddOptions struct {
+		Tags        []string
+		RemindAt    *time.Time
+		RepeatEvery int
+		Due         *time.Time
+		Recurrence  string
+		Priority    Priority
+		Project     string
+		// ParentId makes the task a subtask of another, zero for a top level task
+		ParentId int
 	}
 
 	// Tasks represents a list of Task object
 	Tasks []Task
+
+	// List is the task list of a storage, its changes are saved to the
+	// storage it was loaded from. The methods of Tasks read it.
+	List struct {
+		Tasks
+		storage Storage
+		// before is the list as it was before the running change, it is
+		// put back when the change can not be saved
+		before Tasks
+	}
 )
 
 const (
 	// dbFileName is the default storage file path
 	dbFileName = ".task.json"
-	// timeLayout default time layout for task application
-	timeLayout = "Mon, 01/02/06, 03:04PM"
 )
 
-var mutex sync.Mutex
+var (
+	mutex sync.Mutex
+	// updateMutex serializes the read-modify-write cycles of this process
+	updateMutex sync.Mutex
+)
 
-// New return a Task list instance
-func New() Tasks {
-	return readDBFile()
+// New return a Task list instance loaded from the given storage,
+// all the subsequent changes are persisted to the same storage
+func New(s Storage) (*List, error) {
+	tasks, e := s.Load()
+	if e != nil {
+		return nil, storageError(e)
+	}
+	return &List{Tasks: tasks, storage: s}, nil
+}
+
+//Add create a new task, the reminder time is stored in UTC
+func (l *List) Add(description string, tags []string, remind *time.Time) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if remind != nil {
+		remind = timePtr(remind.UTC())
+	}
+	_t := Task{Id: l.GetNextId(), UID: uid(), Description: description, Tags: normalizeTags(tags), Created: now(), RemindAt: remind}
+	l.Tasks = append(l.Tasks, _t)
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return _t, nil
 }
 
-//Add create a new task
-func (t *Tasks) Add(description, tag string, remind string) Task {
-	_t := Task{Id: t.GetNextId(), UID: uid(), Description: description, Tag: tag, Created: time.Now().Format(timeLayout), RemindAt: remind, Completed: ""}
-	*t = append(*t, _t)
-	writeDBFile(*t)
-	return _t
+//AddTask create a new task with all its settings in a single change, nothing
+//is added when one of them is invalid
+func (l *List) AddTask(description string, opts AddOptions) (Task, error) {
+	project, err := ParseProject(opts.Project)
+	if err != nil {
+		return Task{}, err
+	}
+	if opts.RepeatEvery < 0 {
+		return Task{}, fmt.Errorf("invalid repeat interval: %d minutes", opts.RepeatEvery)
+	}
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	_t := Task{
+		Id:          l.GetNextId(),
+		UID:         uid(),
+		Description: description,
+		Tags:        normalizeTags(opts.Tags),
+		Created:     now(),
+		RepeatEvery: opts.RepeatEvery,
+		Priority:    opts.Priority,
+		Project:     project,
+	}
+	if opts.RemindAt != nil {
+		_t.RemindAt = timePtr(opts.RemindAt.UTC())
+	}
+	if opts.Due != nil {
+		_t.Due = timePtr(opts.Due.UTC().Truncate(time.Second))
+	}
+	if err := _t.setRecurrence(opts.Recurrence); err != nil {
+		return Task{}, err
+	}
+	if opts.ParentId != 0 {
+		if err := l.isValidId(opts.ParentId); err != nil {
+			return Task{}, err
+		}
+		p, err := l.getIndexIdNo(opts.ParentId)
+		if err != nil {
+			return Task{}, err
+		}
+		_t.Parent = l.ensureUID(p)
+	}
+	l.Tasks = append(l.Tasks, _t)
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return _t, nil
 }
 
-//GetAllTasks fetch all tasks
+//GetAllTasks fetch all tasks in the DefaultSort order
 func (t Tasks) GetAllTasks() Tasks {
-	sort.Sort(t)
+	t.sortDefault()
 	return t
 }
 
@@ -66,7 +191,7 @@ func (t Tasks) GetAllTasks() Tasks {
 func (t Tasks) GetCompletedTasks() Tasks {
 	var completedTasks Tasks
 	for _, item := range t {
-		if item.Completed != "" {
+		if item.Completed != nil {
 			completedTasks = append(completedTasks, item)
 		}
 	}
@@ -74,15 +199,15 @@ func (t Tasks) GetCompletedTasks() Tasks {
 	return completedTasks
 }
 
-//GetPendingTasks fetch all pending tasks
+//GetPendingTasks fetch all pending tasks in the DefaultSort order
 func (t Tasks) GetPendingTasks() Tasks {
 	var pendingTasks Tasks
 	for _, item := range t {
-		if item.Completed == "" {
+		if item.Completed == nil {
 			pendingTasks = append(pendingTasks, item)
 		}
 	}
-	sort.Sort(pendingTasks)
+	pendingTasks.sortDefault()
 	return pendingTasks
 }
 
@@ -90,13 +215,94 @@ func (t Tasks) GetPendingTasks() Tasks {
 func (t Tasks) GetReminderTasks() Tasks {
 	var reminderList Tasks
 	for _, item := range t {
-		if item.RemindAt != "" && item.Completed == "" {
+		if item.HasReminder() && item.Completed == nil {
 			reminderList = append(reminderList, item) //only uncompleted reminder
 		}
 	}
+	sort.Sort(reminderList)
 	return reminderList
 }
 
+//GetCompletedTasks fetch all completed tasks of the list, a Querier storage
+//serves it from its index
+func (l *List) GetCompletedTasks() Tasks {
+	if tasks, ok := l.query(Querier.CompletedTasks); ok {
+		return tasks
+	}
+	return l.Tasks.GetCompletedTasks()
+}
+
+//GetPendingTasks fetch all pending tasks of the list in the DefaultSort order,
+//a Querier storage serves it from its index
+func (l *List) GetPendingTasks() Tasks {
+	if tasks, ok := l.query(Querier.PendingTasks); ok {
+		tasks.sortDefault()
+		return tasks
+	}
+	return l.Tasks.GetPendingTasks()
+}
+
+//GetReminderTasks fetch all the reminder tasks of the list, a Querier storage
+//serves it from its index
+func (l *List) GetReminderTasks() Tasks {
+	if tasks, ok := l.query(Querier.ReminderTasks); ok {
+		return tasks
+	}
+	return l.Tasks.GetReminderTasks()
+}
+
+//GetDueReminders fetch the tasks with a reminder that is due at now and was never
+//notified, including the overdue ones missed while the listener was not running
+func (t Tasks) GetDueReminders(now time.Time) Tasks {
+	var dueList Tasks
+	for _, item := range t.GetReminderTasks() {
+		for _, alarm := range item.Alarms() {
+			if alarm.NotifiedAt == nil && !alarm.At.After(now) {
+				dueList = append(dueList, item)
+				break
+			}
+		}
+	}
+	return dueList
+}
+
+//GetOverdueTasks fetch the pending tasks that were due before now, the most overdue first
+func (t Tasks) GetOverdueTasks(now time.Time) Tasks {
+	var overdueList Tasks
+	for _, item := range t.GetPendingTasks() {
+		if item.Due != nil && item.Due.Before(now) {
+			overdueList = append(overdueList, item)
+		}
+	}
+	sort.SliceStable(overdueList, func(i, j int) bool { return overdueList[i].Due.Before(*overdueList[j].Due) })
+	return overdueList
+}
+
+//GetAlarms fetch every reminder of the pending tasks as its own entry, ordered by time
+func (t Tasks) GetAlarms() []Alarm {
+	var alarms []Alarm
+	for _, item := range t.GetReminderTasks() {
+		alarms = append(alarms, item.Alarms()...)
+	}
+	sort.SliceStable(alarms, func(i, j int) bool { return alarms[i].At.Before(alarms[j].At) })
+	return alarms
+}
+
+//GetMissedReminders fetch every reminder that was notified more than late after its time
+func (t Tasks) GetMissedReminders(late time.Duration) []Alarm {
+	tasks := append(Tasks{}, t...)
+	sort.Sort(tasks)
+	var missed []Alarm
+	for _, item := range tasks {
+		for _, alarm := range item.Alarms() {
+			if alarm.NotifiedAt != nil && alarm.NotifiedAt.Sub(alarm.At) > late {
+				missed = append(missed, alarm)
+			}
+		}
+	}
+	return missed
+}
+
 //GetTask fetch a single task
 func (t Tasks) GetTask(id int) (Task, error) {
 	if err := t.isValidId(id); err != nil {
@@ -110,77 +316,369 @@ func (t Tasks) GetTask(id int) (Task, error) {
 }
 
 //UpdateTask update a task by id
-func (t *Tasks) UpdateTask(id int, description string) (string, error) {
-	if err := t.isValidId(id); err != nil {
+func (l *List) UpdateTask(id int, description string) (string, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
 		return fmt.Sprintf("Unable to update %s", description), err
 	}
-	i, err := t.getIndexIdNo(id)
+	i, err := l.getIndexIdNo(id)
 	if err != nil {
 		return "", err
 	}
-	oldDescription := (*t)[i].Description
-	(*t)[i].Description = description
-	(*t)[i].Updated = time.Now().Format(timeLayout)
-	writeDBFile(*t)
+	oldDescription := l.Tasks[i].Description
+	l.Tasks[i].Description = description
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("Task Updated: %s --> %s", oldDescription, description), nil
 }
 
-//UpdateTaskTag update a task's tag by id
-func (t *Tasks) UpdateTaskTag(id int, tag string) (string, error) {
-	if err := t.isValidId(id); err != nil {
+//UpdateTaskTag replace the tags of a task by a single one, an empty tag clears them
+func (l *List) UpdateTaskTag(id int, tag string) (string, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
 		return fmt.Sprintf("Unable to update %s", tag), err
 	}
-	i, err := t.getIndexIdNo(id)
+	i, err := l.getIndexIdNo(id)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
-	oldTag := (*t)[i].Tag
-	(*t)[i].Tag = tag
-	(*t)[i].Updated = time.Now().Format(timeLayout)
-	writeDBFile(*t)
-	return fmt.Sprintf("Task Updated: %s --> %s", oldTag, tag), nil
+	oldTags := strings.Join(l.Tasks[i].Tags, " ")
+	l.Tasks[i].Tags = normalizeTags([]string{tag})
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Task Updated: %s --> %s", oldTags, tag), nil
 }
 
-//MarkAsCompleteTask mark a task as completed by id
-func (t *Tasks) MarkAsCompleteTask(id int) (Task, error) {
-	if err := t.isValidId(id); err != nil {
+//MarkAsCompleteTask mark a task as completed by id, a recurring task gets its
+//next occurrence added with a new id and uid. A task with pending subtasks
+//is not completed, see ForceCompleteTask.
+func (l *List) MarkAsCompleteTask(id int) (Task, error) {
+	return l.completeTask(id, false)
+}
+
+//MarkAsNotifiedTask record that the reminder of a task has been delivered
+func (l *List) MarkAsNotifiedTask(id int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
 		return Task{}, err
 	}
-	i, err := t.getIndexIdNo(id)
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	l.Tasks[i].NotifiedAt = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//SnoozeTask reschedule the reminder of a pending task, a fired reminder fires
+//again. A completed task can not be snoozed, mark it as pending first.
+func (l *List) SnoozeTask(id int, until time.Time) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
 	if err != nil {
 		return Task{}, err
 	}
-	(*t)[i].Completed = time.Now().Format(timeLayout)
-	writeDBFile(*t)
-	return (*t)[i], nil
+	if l.Tasks[i].Completed != nil {
+		return Task{}, fmt.Errorf("%w: id %d", ErrCompleted, id)
+	}
+	l.Tasks[i].RemindAt = timePtr(until.UTC().Truncate(time.Second))
+	l.Tasks[i].NotifiedAt = nil
+	// the reminders relative to RemindAt move along with it
+	if l.Tasks[i].Due == nil {
+		l.Tasks[i].resetRelativeReminders()
+	}
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//AddReminder add an extra reminder to a task, a reminder before the task's
+//time needs the task to have a RemindAt
+func (l *List) AddReminder(id int, r Reminder) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	switch {
+	case r.At != nil:
+		r.At = timePtr(r.At.UTC().Truncate(time.Second))
+		r.Before = 0
+	case r.Before < 0:
+		return Task{}, fmt.Errorf("invalid reminder: %d minutes before", r.Before)
+	case l.Tasks[i].anchor() == nil:
+		return Task{}, fmt.Errorf("%w: id %d, a reminder before it needs a due date or time", ErrNoReminder, id)
+	}
+	r.NotifiedAt = nil
+	l.Tasks[i].Reminders = append(l.Tasks[i].Reminders, r)
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//UpdateTaskDue set when a task is due in UTC, nil clears it. The reminders
+//before the due date move along with it.
+func (l *List) UpdateTaskDue(id int, due *time.Time) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if due != nil {
+		due = timePtr(due.UTC().Truncate(time.Second))
+	}
+	l.Tasks[i].Due = due
+	l.Tasks[i].resetRelativeReminders()
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//UpdateTaskRecurrence make a task recur by a RRULE, empty stops it. A task
+//without due date is due at the first occurrence and is reminded at its due
+//date, so every occurrence is notified.
+func (l *List) UpdateTaskRecurrence(id int, rule string) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := l.Tasks[i].setRecurrence(rule); err != nil {
+		return Task{}, err
+	}
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//MarkAsNotifiedReminder record that a single reminder of a task has been delivered,
+//index 0 is the task's RemindAt and n is Reminders[n-1] like Alarm.Index
+func (l *List) MarkAsNotifiedReminder(id, index int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	switch {
+	case index == 0:
+		l.Tasks[i].NotifiedAt = timePtr(now())
+	case index > 0 && index <= len(l.Tasks[i].Reminders):
+		l.Tasks[i].Reminders[index-1].NotifiedAt = timePtr(now())
+	default:
+		return Task{}, fmt.Errorf("%w: reminder %d of id %d", ErrNotFound, index, id)
+	}
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//make the task recur by a RRULE, empty stops it. Without due date it is due
+//at the first occurrence and it is reminded at its due date.
+func (task *Task) setRecurrence(rule string) error {
+	if rule != "" {
+		if err := validRule(rule); err != nil {
+			return err
+		}
+		if task.Due == nil {
+			first, ok, err := NextOccurrence(rule, now().In(time.Local), now())
+			if err != nil || !ok {
+				return fmt.Errorf("schedule %q has no next occurrence", rule)
+			}
+			task.Due = timePtr(first.UTC())
+		}
+		if task.RemindAt == nil {
+			task.RemindAt = timePtr(*task.Due)
+		}
+	}
+	task.Recurrence = rule
+	return nil
+}
+
+//remindAgain move the RemindAt of a repeating reminder after it fired, unlike
+//SnoozeTask the other reminders and the task state are left alone
+func (l *List) remindAgain(id int, at time.Time) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	l.Tasks[i].RemindAt = timePtr(at.UTC().Truncate(time.Second))
+	l.Tasks[i].NotifiedAt = nil
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//RepeatTask make the reminder of a task fire again every given minutes until
+//it is acknowledged, zero minutes fires it only once
+func (l *List) RepeatTask(id int, minutes int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	if minutes < 0 {
+		return Task{}, fmt.Errorf("invalid repeat interval: %d minutes", minutes)
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	l.Tasks[i].RepeatEvery = minutes
+	l.Tasks[i].Updated = timePtr(now())
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
+}
+
+//AckTask acknowledge the reminder of a task, it stops repeating. Only a
+//reminder that fired or repeats can be acknowledged.
+func (l *List) AckTask(id int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return Task{}, err
+	}
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if l.Tasks[i].RemindAt == nil {
+		return Task{}, fmt.Errorf("%w: id %d", ErrNoReminder, id)
+	}
+	if l.Tasks[i].RepeatEvery <= 0 && l.Tasks[i].RemindAt.After(now()) {
+		return Task{}, fmt.Errorf("%w: id %d has not fired yet", ErrNoReminder, id)
+	}
+	if l.Tasks[i].NotifiedAt == nil {
+		l.Tasks[i].NotifiedAt = timePtr(now())
+	}
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
 }
 
 //MarkAsPendingTask mark a task as pending by id
-func (t *Tasks) MarkAsPendingTask(id int) (Task, error) {
-	if err := t.isValidId(id); err != nil {
+func (l *List) MarkAsPendingTask(id int) (Task, error) {
+	unlock, err := l.lock()
+	if err != nil {
 		return Task{}, err
 	}
-	i, err := t.getIndexIdNo(id)
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return Task{}, err
+	}
+	i, err := l.getIndexIdNo(id)
 	if err != nil {
 		return Task{}, err
 	}
-	(*t)[i].Completed = ""
-	writeDBFile(*t)
-	return (*t)[i], nil
+	l.Tasks[i].Completed = nil
+	if err := l.save(); err != nil {
+		return Task{}, err
+	}
+	return l.Tasks[i], nil
 }
 
-//RemoveTask delete a task by id
-func (t *Tasks) RemoveTask(id int) error {
-	if err := t.isValidId(id); err != nil {
+//RemoveTask delete a task by id, its subtasks move up to its parent, see
+//RemoveTaskTree to delete them as well
+func (l *List) RemoveTask(id int) error {
+	unlock, err := l.lock()
+	if err != nil {
 		return err
 	}
-	i, err := t.getIndexIdNo(id)
+	defer unlock()
+	if err := l.isValidId(id); err != nil {
+		return err
+	}
+	i, err := l.getIndexIdNo(id)
 	if err != nil {
 		return err
 	}
-	*t = append((*t)[:i], (*t)[i+1:]...)
-	writeDBFile(*t)
-	return nil
+	removed := l.Tasks[i]
+	l.Tasks = append(l.Tasks[:i], l.Tasks[i+1:]...)
+	if removed.UID != "" {
+		for j := range l.Tasks {
+			if l.Tasks[j].Parent == removed.UID {
+				l.Tasks[j].Parent = removed.Parent
+			}
+		}
+	}
+	return l.save()
 }
 
 //TotalTask return total task count
@@ -192,7 +690,7 @@ func (t Tasks) TotalTask() int {
 func (t Tasks) CompletedTask() int {
 	completedTask := 0
 	for _, i := range t {
-		if i.Completed != "" {
+		if i.Completed != nil {
 			completedTask++
 		}
 	}
@@ -226,16 +724,10 @@ func (t Tasks) GetNextId() int {
 //check if id valid
 func (t Tasks) isValidId(id int) error {
 	if id < 0 || id == 0 {
-		return errors.New("Negative id not accepted!")
+		return fmt.Errorf("%w: %d", ErrInvalidId, id)
 	}
-	index, err := t.getIndexIdNo(id)
-	if err != nil {
-		return err
-	}
-	if index > len(t) {
-		return errors.New("Id " + strconv.Itoa(id) + " not exist!")
-	}
-	return nil
+	_, err := t.getIndexIdNo(id)
+	return err
 }
 
 // get indexIdNo from id
@@ -245,14 +737,66 @@ func (t Tasks) getIndexIdNo(id int) (int, error) {
 			return i, nil
 		}
 	}
-	return 0, errors.New("Invalid Id!")
+	return 0, fmt.Errorf("%w: id %d", ErrNotFound, id)
 }
 
 //FlushDB flush task database
-func (t *Tasks) FlushDB() error {
-	*t = Tasks{}
-	removeDBFileIfExist()
-	createDBFileIfNotExist()
+func (l *List) FlushDB() error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	l.Tasks = Tasks{}
+	return l.save()
+}
+
+//run an indexed query when the storage of the list supports it
+func (l *List) query(fn func(Querier) (Tasks, error)) (Tasks, bool) {
+	q, ok := l.storage.(Querier)
+	if !ok {
+		return nil, false
+	}
+	tasks, err := fn(q)
+	if err != nil {
+		return nil, false
+	}
+	sort.Sort(tasks)
+	return tasks, true
+}
+
+//lock the storage for a read-modify-write cycle and reload the task list,
+//the lock is held across processes when the storage is a Locker
+func (l *List) lock() (func(), error) {
+	updateMutex.Lock()
+	unlock := func() { updateMutex.Unlock() }
+	if locker, ok := l.storage.(Locker); ok {
+		release, e := locker.Lock()
+		if e != nil {
+			unlock()
+			return nil, storageError(e)
+		}
+		unlock = func() {
+			release()
+			updateMutex.Unlock()
+		}
+	}
+	tasks, e := l.storage.Load()
+	if e != nil {
+		unlock()
+		return nil, storageError(e)
+	}
+	l.before, l.Tasks = l.Tasks, tasks
+	return unlock, nil
+}
+
+//persist the task list to its storage, the list is left as it was when
+//that fails
+func (l *List) save() error {
+	if e := l.storage.Save(l.Tasks); e != nil {
+		l.Tasks = l.before
+		return storageError(e)
+	}
 	return nil
 }
 
@@ -272,82 +816,80 @@ func (t Tasks) Swap(i, j int) {
 	t[i], t[j] = t[j], t[i]
 }
 
-//===========================helpers
-//generate a uid
-func uid() string {
-	uuid := make([]byte, 16)
-	n, err := io.ReadFull(rand.Reader, uuid)
-	if n != len(uuid) || err != nil {
-		return ""
-	}
-	// variant bits; see section 4.1.1
-	uuid[8] = uuid[8]&^0xc0 | 0x80
-	// version 4 (pseudo-random); see section 4.1.3
-	uuid[6] = uuid[6]&^0xf0 | 0x40
-	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+//HasReminder tell whether a task has any reminder
+func (task Task) HasReminder() bool {
+	return task.RemindAt != nil || len(task.Reminders) > 0
 }
 
-//get file path
-func dbFile() string {
-	env := os.Getenv("TASK_DB_FILE_PATH")
-	if env != "" {
-		if strings.HasSuffix(env, ".json") {
-			return env
+//Alarms resolve every reminder of a task to its time, the reminders before
+//the task's time are skipped while it has neither Due nor RemindAt
+func (task Task) Alarms() []Alarm {
+	var alarms []Alarm
+	if task.RemindAt != nil {
+		alarms = append(alarms, Alarm{Task: task, Index: 0, At: *task.RemindAt, NotifiedAt: task.NotifiedAt})
+	}
+	for n, r := range task.Reminders {
+		alarm := Alarm{Task: task, Index: n + 1, NotifiedAt: r.NotifiedAt}
+		switch {
+		case r.At != nil:
+			alarm.At = *r.At
+		case task.anchor() != nil:
+			alarm.At = task.anchor().Add(-time.Duration(r.Before) * time.Minute)
+		default:
+			continue
 		}
-		return filepath.Join(filepath.Clean(env), dbFileName)
+		alarms = append(alarms, alarm)
 	}
+	return alarms
+}
 
-	usr, err := user.Current()
-	if err != nil {
-		panic(err)
-
+//the time the reminders before are relative to, Due or else RemindAt
+func (task Task) anchor() *time.Time {
+	if task.Due != nil {
+		return task.Due
 	}
-	return filepath.Join(usr.HomeDir, dbFileName)
+	return task.RemindAt
 }
 
-//load database
-func readDBFile() Tasks {
-	//load the json to task
-	mutex.Lock()
-	defer mutex.Unlock()
-	file, e := ioutil.ReadFile(dbFile())
-	if e != nil {
-		fmt.Printf("File error: %v\n", e)
-		os.Exit(1)
+//the reminders before the task's time fire again once it moves
+func (task *Task) resetRelativeReminders() {
+	for n := range task.Reminders {
+		if task.Reminders[n].At == nil {
+			task.Reminders[n].NotifiedAt = nil
+		}
 	}
-	var tasks Tasks
-	json.Unmarshal(file, &tasks)
-	return tasks
 }
 
-//write to json
-func writeDBFile(tasks Tasks) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	removeDBFileIfExist()
-	taskJson, _ := json.Marshal(tasks)
-	e := ioutil.WriteFile(dbFile(), taskJson, 0644)
-	if e != nil {
-		fmt.Printf("File error: %v\n", e)
-		os.Exit(1)
-	}
+//===========================helpers
+//current time, stored with second precision
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
 }
 
-//create a db file if not exist
-func createDBFileIfNotExist() {
-	if _, err := os.Stat(dbFile()); os.IsNotExist(err) {
-		os.Create(dbFile())
-	}
+//pointer to a time, for the optional fields
+func timePtr(t time.Time) *time.Time {
+	return &t
 }
 
-//delete a db file if exist
-func removeDBFileIfExist() {
-	if _, err := os.Stat(dbFile()); !os.IsNotExist(err) {
-		os.Remove(dbFile())
+//the uid of the task at index i, tasks of older databases may have no uid to
+//point at so one is given
+func (t *Tasks) ensureUID(i int) string {
+	if (*t)[i].UID == "" {
+		(*t)[i].UID = uid()
 	}
+	return (*t)[i].UID
 }
 
-func init() {
-	//create .task.json file if not exist
-	createDBFileIfNotExist()
+//generate a uid
+func uid() string {
+	uuid := make([]byte, 16)
+	n, err := io.ReadFull(rand.Reader, uuid)
+	if n != len(uuid) || err != nil {
+		return ""
+	}
+	// variant bits; see section 4.1.1
+	uuid[8] = uuid[8]&^0xc0 | 0x80
+	// version 4 (pseudo-random); see section 4.1.3
+	uuid[6] = uuid[6]&^0xf0 | 0x40
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
 }
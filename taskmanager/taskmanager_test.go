@@ -1,46 +1,246 @@
 package taskmanager
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 var tasksList = []struct {
 	description string
 	uuid        string
-	tag         string
-	remindAt    string
+	tags        []string
+	remindAt    *time.Time
 }{
 	{
-		description: "Go to store", tag: "low", remindAt: "",
+		description: "Go to store", tags: []string{"low"}, remindAt: nil,
 	},
 	{
-		description: "Learn golang testing", tag: "high", remindAt: "",
+		description: "Learn golang testing", tags: []string{"high"}, remindAt: nil,
 	},
 	{
-		description: "Watch Pirates of the carribean", tag: "medium", remindAt: "",
+		description: "Watch Pirates of the carribean", tags: []string{"medium", "movie"}, remindAt: nil,
 	},
 }
 
-var tm Tasks
+var tm *List
 
 func TestMain(m *testing.M) {
-	createDBFileIfNotExist()
-	tm = New()
+	// the default storage of the tests lives in a temporary directory, the
+	// database of the user is never touched
+	dir, err := ioutil.TempDir("", "task")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("TASK_DB_FILE_PATH", dir)
+	s, err := DefaultStorage()
+	if err != nil {
+		panic(err)
+	}
+	tm, err = New(s)
+	if err != nil {
+		panic(err)
+	}
 	m.Run()
-	removeDBFileIfExist()
+	os.RemoveAll(dir)
 }
 
 func TestTasks_Add(t *testing.T) {
-	for _, t := range tasksList {
-		tm.Add(t.description, t.tag, t.remindAt)
+	for _, task := range tasksList {
+		if _, err := tm.Add(task.description, task.tags, task.remindAt); err != nil {
+			t.Error("Unable to add task", err)
+		}
 	}
 	if 3 != len(tasksList) {
 		t.Error("Task count does not matched!")
 	}
 }
 
+func TestTasks_Add_reminderInUTC(t *testing.T) {
+	tasks, _ := New(&memoryStorage{})
+	at := time.Date(2017, 7, 22, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	task, err := tasks.Add("Meeting with John", nil, &at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.RemindAt.Location() != time.UTC || !task.RemindAt.Equal(at) {
+		t.Error("Reminder must be stored in UTC at the same instant!")
+	}
+}
+
+func TestTasks_AddTask(t *testing.T) {
+	ms := &memoryStorage{tasks: Tasks{{Id: 1, Description: "Release 2.0"}}}
+	tasks, _ := New(ms)
+	due := time.Date(2017, 7, 22, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	task, err := tasks.AddTask("Write the changelog", AddOptions{
+		Tags: []string{"Docs"}, Due: &due, Recurrence: "FREQ=WEEKLY", Priority: PriorityHigh, Project: "Work", ParentId: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.saves != 1 || len(ms.tasks) != 2 {
+		t.Error("New task must be saved once!", ms.saves)
+	}
+	if !task.HasTag("docs") || !task.Due.Equal(due) || *task.RemindAt != *task.Due || task.Priority != PriorityHigh ||
+		task.Project != "work" || task.Parent == "" || task.Parent != tasks.Tasks[0].UID {
+		t.Error("New task must get all its settings!", task)
+	}
+	if _, err := tasks.AddTask("Tag the release", AddOptions{ParentId: 5}); !errors.Is(err, ErrNotFound) {
+		t.Error("Missing parent must fail", err)
+	}
+	if _, err := tasks.AddTask("Tag the release", AddOptions{Recurrence: "FREQ=SOMETIMES"}); err == nil {
+		t.Error("Invalid recurrence must fail")
+	}
+	if ms.saves != 1 || len(tasks.Tasks) != 2 {
+		t.Error("Invalid task must not be added!", tasks.Tasks)
+	}
+}
+
+func TestTasks_GetDueReminders(t *testing.T) {
+	now := time.Now()
+	tasks, _ := New(&memoryStorage{tasks: Tasks{
+		{Id: 1, Description: "Missed while asleep", RemindAt: timePtr(now.Add(-3 * time.Hour))},
+		{Id: 2, Description: "Due now", RemindAt: timePtr(now)},
+		{Id: 3, Description: "Upcoming", RemindAt: timePtr(now.Add(time.Hour))},
+		{Id: 4, Description: "Already notified", RemindAt: timePtr(now.Add(-time.Hour)), NotifiedAt: timePtr(now)},
+	}})
+	if due := tasks.GetDueReminders(now); len(due) != 2 || due[0].Id+due[1].Id != 3 {
+		t.Error("Failed to get the due and overdue reminders!", due)
+	}
+	if _, err := tasks.MarkAsNotifiedTask(1); err != nil {
+		t.Fatal(err)
+	}
+	if due := tasks.GetDueReminders(now); len(due) != 1 || due[0].Id != 2 {
+		t.Error("Notified reminder must not be due again!")
+	}
+	missed := tasks.GetMissedReminders(2 * time.Minute)
+	if len(missed) != 2 || missed[0].Task.Id != 4 || missed[1].Task.Id != 1 {
+		t.Error("Failed to get the reminders delivered late!", missed)
+	}
+	// an extra reminder delivered late is missed on its own
+	tasks.Tasks[2].Reminders = []Reminder{{Before: 30, NotifiedAt: timePtr(now)}, {Before: 5, NotifiedAt: timePtr(now.Add(time.Hour))}}
+	missed = tasks.GetMissedReminders(2 * time.Minute)
+	if len(missed) != 3 || missed[0].Task.Id != 4 || missed[1].Task.Id != 3 || missed[1].Index != 2 || missed[2].Task.Id != 1 {
+		t.Error("Failed to get the extra reminder delivered late!", missed)
+	}
+}
+
+func TestTasks_SnoozeTask(t *testing.T) {
+	now := time.Now()
+	tasks, _ := New(&memoryStorage{tasks: Tasks{
+		{Id: 1, Description: "Fired", RemindAt: timePtr(now.Add(-time.Hour)), NotifiedAt: timePtr(now)},
+		{Id: 2, Description: "No reminder"},
+		{Id: 3, Description: "Fired and completed", RemindAt: timePtr(now.Add(-time.Hour)), NotifiedAt: timePtr(now), Completed: timePtr(now)},
+	}})
+	task, err := tasks.SnoozeTask(1, now.Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tasks.SnoozeTask(3, now.Add(10*time.Minute)); !errors.Is(err, ErrCompleted) || tasks.Tasks[2].Completed == nil {
+		t.Error("Snoozing a completed task must return ErrCompleted and keep it completed", err)
+	}
+	if task.NotifiedAt != nil || task.Completed != nil || task.RemindAt.Location() != time.UTC {
+		t.Error("Snoozed reminder must be pending and not yet notified!", task)
+	}
+	if due := tasks.GetDueReminders(now.Add(10 * time.Minute)); len(due) != 1 || due[0].Id != 1 {
+		t.Error("Snoozed reminder must be due again!")
+	}
+	if _, err := tasks.AckTask(2); !errors.Is(err, ErrNoReminder) {
+		t.Error("Acknowledging a task without reminder must return ErrNoReminder", err)
+	}
+	if _, err := tasks.AckTask(1); !errors.Is(err, ErrNoReminder) {
+		t.Error("Acknowledging a reminder that has not fired must return ErrNoReminder", err)
+	}
+	if _, err := tasks.RepeatTask(1, 5); err != nil {
+		t.Fatal(err)
+	}
+	task, err = tasks.AckTask(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.NotifiedAt == nil || len(tasks.GetDueReminders(now.Add(time.Hour))) != 0 {
+		t.Error("Acknowledged reminder must not fire again!")
+	}
+}
+
+func TestTasks_AddReminder(t *testing.T) {
+	deadline := time.Date(2017, 7, 22, 10, 30, 0, 0, time.UTC)
+	tasks, _ := New(&memoryStorage{tasks: Tasks{
+		{Id: 1, Description: "Submit the report", RemindAt: timePtr(deadline)},
+		{Id: 2, Description: "No reminder"},
+	}})
+	if _, err := tasks.AddReminder(1, Reminder{Before: 24 * 60}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tasks.AddReminder(1, Reminder{At: timePtr(deadline.Add(-time.Hour))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tasks.AddReminder(2, Reminder{Before: 60}); !errors.Is(err, ErrNoReminder) {
+		t.Error("Reminder before a task without time must return ErrNoReminder", err)
+	}
+	alarms := tasks.GetAlarms()
+	if len(alarms) != 3 || alarms[0].Index != 1 || alarms[1].Index != 2 || alarms[2].Index != 0 {
+		t.Fatal("Every reminder must be its own alarm, ordered by time!", alarms)
+	}
+	if !alarms[0].At.Equal(deadline.Add(-24 * time.Hour)) {
+		t.Error("Reminder before must be relative to the task's time!")
+	}
+	task, err := tasks.MarkAsNotifiedReminder(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Reminders[0].NotifiedAt == nil || task.NotifiedAt != nil || task.Completed != nil {
+		t.Error("Only the fired reminder must be notified and the task must stay pending!")
+	}
+	if _, err := tasks.MarkAsNotifiedReminder(1, 3); !errors.Is(err, ErrNotFound) {
+		t.Error("Unknown reminder must return ErrNotFound", err)
+	}
+	if due := tasks.GetDueReminders(deadline.Add(-time.Hour)); len(due) != 1 {
+		t.Error("Task with a due absolute reminder must be due!")
+	}
+	// snoozing moves the relative reminders along, the absolute ones stay
+	task, _ = tasks.SnoozeTask(1, deadline.Add(48*time.Hour))
+	if task.Reminders[0].NotifiedAt != nil || !task.Alarms()[1].At.Equal(deadline.Add(24*time.Hour)) {
+		t.Error("Relative reminder must move with the snoozed time!")
+	}
+}
+
+func TestTasks_GetOverdueTasks(t *testing.T) {
+	now := time.Now()
+	tasks, _ := New(&memoryStorage{tasks: Tasks{
+		{Id: 1, Description: "Overdue", Due: timePtr(now.Add(-time.Hour))},
+		{Id: 2, Description: "Long overdue", Due: timePtr(now.Add(-48 * time.Hour))},
+		{Id: 3, Description: "Completed", Due: timePtr(now.Add(-time.Hour)), Completed: timePtr(now)},
+		{Id: 4, Description: "Upcoming", Due: timePtr(now.Add(time.Hour))},
+		{Id: 5, Description: "No due date", RemindAt: timePtr(now.Add(-time.Hour))},
+	}})
+	if overdue := tasks.GetOverdueTasks(now); len(overdue) != 2 || overdue[0].Id != 2 || overdue[1].Id != 1 {
+		t.Error("Failed to get the overdue tasks, most overdue first!", overdue)
+	}
+	// a due date takes over as the time the reminders before are relative to
+	if _, err := tasks.AddReminder(5, Reminder{Before: 60}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := tasks.UpdateTaskDue(5, timePtr(now.Add(24*time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alarms := task.Alarms(); len(alarms) != 2 || !alarms[1].At.Equal(task.Due.Add(-time.Hour)) {
+		t.Error("Reminder before must be relative to the due date!")
+	}
+	if task.RemindAt == nil || task.Completed != nil {
+		t.Error("Setting the due date must not touch the reminder!")
+	}
+	if task, _ := tasks.UpdateTaskDue(5, nil); task.Due != nil {
+		t.Error("Failed to clear the due date!")
+	}
+}
+
 func TestTasks_GetAllTasks(t *testing.T) {
 	tasks := tm.GetAllTasks()
 	if len(tasks) != 3 {
@@ -48,6 +248,15 @@ func TestTasks_GetAllTasks(t *testing.T) {
 	}
 }
 
+func TestTasks_GetTask_notFound(t *testing.T) {
+	if _, err := tm.GetTask(100); !errors.Is(err, ErrNotFound) {
+		t.Error("Missing task must return ErrNotFound")
+	}
+	if _, err := tm.GetTask(-1); !errors.Is(err, ErrInvalidId) {
+		t.Error("Negative id must return ErrInvalidId")
+	}
+}
+
 func TestTasks_GetTask(t *testing.T) {
 	taskId := 3
 	task, err := tm.GetTask(taskId)
@@ -74,6 +283,37 @@ func TestTasks_UpdateTaskTag(t *testing.T) {
 	if err != nil {
 		t.Error("Unable to Update task tag")
 	}
+	if task, _ := tm.GetTask(1); len(task.Tags) != 1 || !task.HasTag("+Important") {
+		t.Error("Tag must replace the tags of the task", task.Tags)
+	}
+}
+
+func TestTasks_TagTask(t *testing.T) {
+	task, err := tm.TagTask(3, []string{"+Weekend", "movie", "fun"}, []string{"medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(task.Tags, []string{"movie", "weekend", "fun"}) {
+		t.Error("Unexpected tags", task.Tags)
+	}
+	if tagged := tm.GetTasksByTags("movie", "+fun"); len(tagged) != 1 || tagged[0].Id != 3 {
+		t.Error("Failed to filter tasks by tags", tagged)
+	}
+	if _, err := tm.TagTask(100, []string{"x"}, nil); !errors.Is(err, ErrNotFound) {
+		t.Error("Tagging a missing task must fail", err)
+	}
+}
+
+func TestTasks_GetTags(t *testing.T) {
+	tasks := Tasks{
+		{Id: 1, Tags: []string{"backend", "urgent"}},
+		{Id: 2, Tags: []string{"backend"}, Completed: timeAt("2017-07-21T12:13:00Z")},
+		{Id: 3, Tags: []string{"frontend"}},
+	}
+	expected := []TagCount{{Tag: "backend", Pending: 1, Total: 2}, {Tag: "frontend", Pending: 1, Total: 1}, {Tag: "urgent", Pending: 1, Total: 1}}
+	if tags := tasks.GetTags(); !reflect.DeepEqual(tags, expected) {
+		t.Error("Unexpected tag counts", tags)
+	}
 }
 
 func TestTasks_MarkAsCompleteTask(t *testing.T) {
@@ -98,7 +338,7 @@ func TestTasks_GetCompletedTasks(t *testing.T) {
 		t.Error("Failed to match the completed task id")
 	}
 	for _, _task := range tasks {
-		if len(_task.Completed) == 0 {
+		if _task.Completed == nil {
 			t.Error("Failed match completed tasks status")
 		}
 	}
@@ -107,7 +347,7 @@ func TestTasks_GetCompletedTasks(t *testing.T) {
 func TestTasks_GetReminderTasks(t *testing.T) {
 	reminders := tm.GetReminderTasks()
 	for _, r := range reminders {
-		if len(r.RemindAt) <= 0 {
+		if r.RemindAt == nil {
 			t.Error("Failed to get reminder tasks!")
 		}
 	}
@@ -175,16 +415,34 @@ func TestTasks_FlushDB(t *testing.T) {
 }
 
 func TestTasks_dbFile(t *testing.T) {
+	defer os.Setenv("TASK_DB_FILE_PATH", os.Getenv("TASK_DB_FILE_PATH"))
+	os.Unsetenv("TASK_DB_FILE_PATH")
 	usr, _ := user.Current()
-	if dbFile() != filepath.Join(filepath.Clean(usr.HomeDir), ".task.json") {
+	if path, _ := dbFilePath(dbFileName); path != filepath.Join(filepath.Clean(usr.HomeDir), ".task.json") {
 		t.Error("Task file path incorrect!")
 	}
+	// the other drivers keep their database next to a json file
+	dir := filepath.Join(os.TempDir(), "tasks")
+	os.Setenv("TASK_DB_FILE_PATH", filepath.Join(dir, "mytasks.json"))
+	for name, expected := range map[string]string{
+		dbFileName:     filepath.Join(dir, "mytasks.json"),
+		boltFileName:   filepath.Join(dir, ".task.db"),
+		sqliteFileName: filepath.Join(dir, ".task.sqlite"),
+	} {
+		if path, _ := dbFilePath(name); path != expected {
+			t.Errorf("Path of %s is %s, expected %s", name, path, expected)
+		}
+	}
+	os.Setenv("TASK_DB_FILE_PATH", dir)
+	if path, _ := dbFilePath(sqliteFileName); path != filepath.Join(dir, ".task.sqlite") {
+		t.Error("Database must be put in the directory!", path)
+	}
 }
 
 func BenchmarkTasks_Add(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		for _, task := range tasksList {
-			tm.Add(task.description, task.tag, task.remindAt)
+			tm.Add(task.description, task.tags, task.remindAt)
 		}
 	}
 }